@@ -1,10 +1,14 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha512"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // Internal Storage object
@@ -14,9 +18,7 @@ var store Storage
 // Can be overwritten by calling RegisterStorage(s Storage) at any time.
 func init() {
 	if store == nil {
-		store = &authStorage{
-			store: make(map[string]authToken),
-		}
+		store = NewMemoryStorage(context.Background(), time.Minute)
 	}
 }
 
@@ -27,6 +29,16 @@ func RegisterStorage(s Storage) {
 	store = s
 }
 
+// Close releases the resources used by the registered storage, like the garbage collector of the default one,
+// if it implements the io.Closer interface.
+func Close() error {
+	if c, ok := store.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
 // generateToken creates a new random token long enough to avoid collisions.
 func generateToken() string {
 	// Generate random bytes
@@ -83,6 +95,11 @@ func GetToken(r *http.Request) string {
 // ValidateToken checks if a token is valid and returns the data contained on it.
 // Otherwise it will return an error status together with an empty string.
 func ValidateToken(token string) (string, error) {
+	// Internal keys (pending tokens, used codes) aren't tokens.
+	if strings.HasPrefix(token, "_") {
+		return "", InvalidKeyError{}
+	}
+
 	return store.Get(token)
 }
 
@@ -90,9 +107,11 @@ func ValidateToken(token string) (string, error) {
 // It sets the same duration time as when it was created, but starting now.
 func RefreshToken(token string) {
 	store.Refresh(token)
+	store.Refresh(fingerprintPrefix + token)
 }
 
 // DeleteToken removes the token data from the storage.
 func DeleteToken(token string) {
 	store.Del(token)
+	store.Del(fingerprintPrefix + token)
 }
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 	"sync"
@@ -119,3 +120,48 @@ func BenchmarkRefreshToken(b *testing.B) {
 		RefreshToken(token)
 	}
 }
+
+func TestMemoryStorageSweep(t *testing.T) {
+	ms := NewMemoryStorage(context.Background(), time.Hour)
+
+	ms.Set("expired", "data", 0)
+	ms.Set("valid", "data", 60)
+
+	ms.Sweep()
+
+	if _, ok := ms.store["expired"]; ok {
+		t.Error("Expired entry not removed by Sweep")
+	}
+	if _, ok := ms.store["valid"]; !ok {
+		t.Error("Valid entry removed by Sweep")
+	}
+
+	// Close waits for the garbage collector to stop
+	ms.Close()
+	select {
+	case <-ms.done:
+	default:
+		t.Error("Garbage collector still running after Close")
+	}
+
+	// Don't restart after Close
+	done := ms.done
+	ms.Set("other", "data", 60)
+	if ms.done != done {
+		t.Error("Garbage collector restarted after Close")
+	}
+}
+
+func TestMemoryStorageContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ms := NewMemoryStorage(ctx, time.Hour)
+
+	ms.Set("key", "data", 60)
+	cancel()
+
+	select {
+	case <-ms.done:
+	case <-time.After(time.Second):
+		t.Error("Garbage collector still running after context cancellation")
+	}
+}
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CookieStore keeps the token data inside the token itself, encrypted and authenticated with AES-GCM,
+// so no server storage is needed. Tokens are sent on the "Auth" cookie read by GetToken.
+// Cookie tokens can't be revoked before they expire, and they can't be bound to a Fingerprint.
+type CookieStore struct {
+	// Encryption keys. The first one encrypts new tokens, all of them are tried to decrypt,
+	// so keys can be rotated by prepending a new one and removing the old ones after the tokens expire.
+	// Each key must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+	// Changes to the Keys take effect on the next token created or validated.
+	Keys [][]byte
+
+	// Max length of the encoded token. Defaults to 4000 bytes, to fit the cookie limit of most browsers.
+	MaxSize int
+
+	// Set the Secure flag on cookies.
+	Secure bool
+
+	// Ciphers initialized from Keys
+	aeads []cipher.AEAD
+
+	// Copy of the Keys used to initialize the ciphers, to detect changes
+	keys [][]byte
+
+	// Sync Mutex
+	sync.Mutex
+}
+
+// NewCookieStore creates a CookieStore using the given keys, the first one being used to encrypt.
+// It returns an error if any key isn't a valid AES key.
+func NewCookieStore(keys ...[]byte) (*CookieStore, error) {
+	cs := &CookieStore{
+		Keys:    keys,
+		MaxSize: 4000,
+	}
+
+	_, err := cs.ciphers()
+	if err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+// ciphers returns the ciphers for the Keys, creating them on the first use and again when the Keys change.
+func (cs *CookieStore) ciphers() ([]cipher.AEAD, error) {
+	cs.Lock()
+	defer cs.Unlock()
+
+	if cs.aeads != nil && sameKeys(cs.keys, cs.Keys) {
+		return cs.aeads, nil
+	}
+
+	if len(cs.Keys) == 0 {
+		return nil, InvalidKeyError{}
+	}
+
+	aeads := make([]cipher.AEAD, len(cs.Keys))
+	keys := make([][]byte, len(cs.Keys))
+	for i, k := range cs.Keys {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+
+		aeads[i], err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[i] = append([]byte(nil), k...)
+	}
+
+	cs.aeads, cs.keys = aeads, keys
+
+	return aeads, nil
+}
+
+// sameKeys reports if both key lists are equal.
+func sameKeys(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewToken encrypts the data together with its expiration in d seconds and returns the resulting token.
+// It returns a TokenSizeError if the token is longer than MaxSize.
+func (cs *CookieStore) NewToken(data string, d int) (string, error) {
+	aeads, err := cs.ciphers()
+	if err != nil {
+		return "", err
+	}
+
+	// Payload: expiration (8 bytes), duration (4 bytes), data.
+	payload := make([]byte, 12+len(data))
+	binary.BigEndian.PutUint64(payload, uint64(time.Now().Add(time.Duration(d)*time.Second).Unix()))
+	binary.BigEndian.PutUint32(payload[8:], uint32(d))
+	copy(payload[12:], data)
+
+	aead := aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, payload, []byte("Auth")))
+
+	if cs.MaxSize > 0 && len(token) > cs.MaxSize {
+		return "", TokenSizeError{}
+	}
+
+	return token, nil
+}
+
+// ValidateToken decrypts the token and returns the data contained on it.
+// If the token can't be decrypted by any key or it has expired, it returns an InvalidKeyError.
+func (cs *CookieStore) ValidateToken(token string) (string, error) {
+	data, _, err := cs.open(token)
+
+	return data, err
+}
+
+// RefreshToken returns a new token with the same data and duration, starting now.
+func (cs *CookieStore) RefreshToken(token string) (string, error) {
+	data, d, err := cs.open(token)
+	if err != nil {
+		return "", err
+	}
+
+	return cs.NewToken(data, d)
+}
+
+// open decrypts and validates a token, returning its data and duration.
+func (cs *CookieStore) open(token string) (string, int, error) {
+	aeads, err := cs.ciphers()
+	if err != nil {
+		return "", 0, err
+	}
+
+	if cs.MaxSize > 0 && len(token) > cs.MaxSize {
+		return "", 0, InvalidKeyError{}
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", 0, InvalidKeyError{}
+	}
+
+	for _, aead := range aeads {
+		if len(raw) < aead.NonceSize() {
+			continue
+		}
+
+		payload, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte("Auth"))
+		if err != nil || len(payload) < 12 {
+			continue
+		}
+
+		// Check expiration
+		if time.Now().Unix() >= int64(binary.BigEndian.Uint64(payload)) {
+			return "", 0, InvalidKeyError{}
+		}
+
+		return string(payload[12:]), int(binary.BigEndian.Uint32(payload[8:])), nil
+	}
+
+	return "", 0, InvalidKeyError{}
+}
+
+// SetCookie writes the "Auth" cookie with the token, valid for d seconds.
+func (cs *CookieStore) SetCookie(w http.ResponseWriter, token string, d int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "Auth",
+		Value:    token,
+		Path:     "/",
+		MaxAge:   d,
+		Expires:  time.Now().Add(time.Duration(d) * time.Second),
+		Secure:   cs.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// DeleteCookie removes the "Auth" cookie from the client.
+func (cs *CookieStore) DeleteCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "Auth",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+		Secure:   cs.Secure,
+		HttpOnly: true,
+	})
+}
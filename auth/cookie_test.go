@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCookieStore(t *testing.T) {
+	cs, err := NewCookieStore(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	token, err := cs.NewToken("someid", 5)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	data, err := cs.ValidateToken(token)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if data != "someid" {
+		t.Error("Token data missmatch")
+	}
+
+	// Tampered
+	b := []byte(token)
+	if b[20] == 'A' {
+		b[20] = 'B'
+	} else {
+		b[20] = 'A'
+	}
+	if _, err := cs.ValidateToken(string(b)); err == nil {
+		t.Error("Tampered token accepted")
+	}
+
+	// Expired
+	token, _ = cs.NewToken("someid", 0)
+	if _, err := cs.ValidateToken(token); err == nil {
+		t.Error("Expired token accepted")
+	}
+
+	// Size limit
+	if _, err := cs.NewToken(strings.Repeat("x", 4000), 5); err == nil {
+		t.Error("Token over MaxSize created")
+	}
+}
+
+func TestCookieStoreRotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte("o"), 16)
+	newKey := bytes.Repeat([]byte("n"), 16)
+
+	old, _ := NewCookieStore(oldKey)
+	token, _ := old.NewToken("someid", 5)
+
+	rotated, _ := NewCookieStore(newKey, oldKey)
+	if data, err := rotated.ValidateToken(token); err != nil || data != "someid" {
+		t.Error("Token encrypted with the old key rejected after rotation")
+	}
+
+	removed, _ := NewCookieStore(newKey)
+	if _, err := removed.ValidateToken(token); err == nil {
+		t.Error("Token encrypted with a removed key accepted")
+	}
+}
+
+func TestCookieStoreLazyInit(t *testing.T) {
+	cs := &CookieStore{Keys: [][]byte{bytes.Repeat([]byte("o"), 16)}}
+
+	// Concurrent first use of a CookieStore created without NewCookieStore.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			token, err := cs.NewToken("someid", 5)
+			if err != nil {
+				t.Error(err.Error())
+			}
+			if _, err := cs.ValidateToken(token); err != nil {
+				t.Error(err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+
+	token, _ := cs.NewToken("someid", 5)
+
+	// Keys replaced after the first use
+	cs.Keys = [][]byte{bytes.Repeat([]byte("n"), 16)}
+	if _, err := cs.ValidateToken(token); err == nil {
+		t.Error("Token encrypted with a replaced key accepted")
+	}
+}
+
+func TestCookieStoreMiddleware(t *testing.T) {
+	cs, _ := NewCookieStore(bytes.Repeat([]byte("k"), 32))
+	a := &Auth{Cookies: cs}
+
+	token, _ := cs.NewToken("someid", 5)
+
+	c := newFingerprintContext("10.0.0.1", "")
+	c.Request.AddCookie(&http.Cookie{Name: "Auth", Value: token})
+	if err := a.PreDispatch(c); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if v, _ := c.Data.Get("_authData"); v != "someid" {
+		t.Error("Token data missmatch")
+	}
+	if c.Response.(*httptest.ResponseRecorder).Header().Get("Set-Cookie") == "" {
+		t.Error("Cookie not refreshed")
+	}
+
+	// Storage tokens aren't valid as cookie tokens
+	c = newFingerprintContext("10.0.0.1", "")
+	c.Request.Header.Set("Auth", NewToken("someid", 5))
+	if err := a.PreDispatch(c); err == nil {
+		t.Error("Storage token accepted by the CookieStore")
+	}
+}
@@ -8,3 +8,17 @@ type InvalidKeyError struct{}
 func (err InvalidKeyError) Error() string {
 	return "Invalid key"
 }
+
+// InvalidCodeError indicates that a second factor code is wrong or has been used already.
+type InvalidCodeError struct{}
+
+func (err InvalidCodeError) Error() string {
+	return "Invalid code"
+}
+
+// TokenSizeError indicates that the data doesn't fit on a cookie token.
+type TokenSizeError struct{}
+
+func (err TokenSizeError) Error() string {
+	return "Token too large"
+}
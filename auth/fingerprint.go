@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/yarf-framework/extras/realip"
+	"github.com/yarf-framework/yarf"
+	"net"
+	"net/url"
+)
+
+// fingerprintPrefix namespaces the token fingerprints inside the storage.
+const fingerprintPrefix = "_fp:"
+
+// Fingerprint binds tokens to the client that created them, to reduce the impact of stolen tokens.
+// Set it on the Auth middleware and create the tokens with its NewToken and NewPendingToken methods.
+// Tokens created without fingerprint are rejected by the Auth middleware using it.
+type Fingerprint struct {
+	// Prefix length used to compare IPv4 client addresses: 32 requires the same address, 24 allows changes within the /24.
+	// 0 ignores IPv4 addresses, and lengths over 32 require the same address.
+	IPv4Prefix int
+
+	// Prefix length used to compare IPv6 client addresses, like 64 to allow changes within the /64.
+	// 0 ignores IPv6 addresses, and lengths over 128 require the same address.
+	IPv6Prefix int
+
+	// Compare the User-Agent header.
+	UserAgent bool
+
+	// Custom function returning any other value to be compared.
+	Func func(c *yarf.Context) string
+
+	// When Flag is true, mismatching requests aren't rejected by the Auth middleware.
+	// Instead, "_authFingerprintMismatch" is set to "true" on the yarf.Context.Data object.
+	Flag bool
+}
+
+// Get calculates the fingerprint of the request.
+// IP addresses are stored complete, so the strictness of the comparison can be changed later.
+// The address is the one resolved by the realip middleware, or the connection remote address.
+func (f *Fingerprint) Get(c *yarf.Context) string {
+	v := url.Values{}
+
+	if f.IPv4Prefix > 0 || f.IPv6Prefix > 0 {
+		v.Set("ip", realip.Verified(c))
+	}
+	if f.UserAgent {
+		v.Set("ua", fmt.Sprintf("%x", sha256.Sum256([]byte(c.Request.UserAgent()))))
+	}
+	if f.Func != nil {
+		v.Set("fn", f.Func(c))
+	}
+
+	return v.Encode()
+}
+
+// Match compares two fingerprints returned by Get using the configured strictness.
+func (f *Fingerprint) Match(stored, actual string) bool {
+	s, err := url.ParseQuery(stored)
+	if err != nil {
+		return false
+	}
+	a, err := url.ParseQuery(actual)
+	if err != nil {
+		return false
+	}
+
+	if f.UserAgent && s.Get("ua") != a.Get("ua") {
+		return false
+	}
+	if f.Func != nil && s.Get("fn") != a.Get("fn") {
+		return false
+	}
+
+	return f.matchIP(s.Get("ip"), a.Get("ip"))
+}
+
+// matchIP compares both IP addresses within the configured prefix lengths.
+func (f *Fingerprint) matchIP(stored, actual string) bool {
+	if f.IPv4Prefix <= 0 && f.IPv6Prefix <= 0 {
+		return true
+	}
+
+	s := net.ParseIP(stored)
+	a := net.ParseIP(actual)
+	if s == nil || a == nil {
+		return stored == actual
+	}
+
+	// Address family change
+	if (s.To4() == nil) != (a.To4() == nil) {
+		return false
+	}
+
+	var mask net.IPMask
+	if s.To4() != nil {
+		if f.IPv4Prefix <= 0 {
+			return true
+		}
+		s, a = s.To4(), a.To4()
+		mask = net.CIDRMask(clamp(f.IPv4Prefix, 32), 32)
+	} else {
+		if f.IPv6Prefix <= 0 {
+			return true
+		}
+		mask = net.CIDRMask(clamp(f.IPv6Prefix, 128), 128)
+	}
+
+	return s.Mask(mask).Equal(a.Mask(mask))
+}
+
+// clamp limits a prefix length to the address size, as longer ones are invalid masks that would match any address.
+func clamp(prefix, bits int) int {
+	if prefix > bits {
+		return bits
+	}
+
+	return prefix
+}
+
+// NewToken creates a new token like the package's NewToken function and binds it to the request fingerprint.
+func (f *Fingerprint) NewToken(c *yarf.Context, data string, d int) string {
+	token := NewToken(data, d)
+
+	store.Set(fingerprintPrefix+token, f.Get(c), d)
+
+	return token
+}
+
+// NewPendingToken creates a new token like the package's NewPendingToken function and binds it to the request fingerprint.
+// The fingerprint is kept when the token gets upgraded by UpgradeToken.
+func (f *Fingerprint) NewPendingToken(c *yarf.Context, data string, d int) string {
+	token := NewPendingToken(data, d)
+
+	store.Set(fingerprintPrefix+token, f.Get(c), d)
+
+	return token
+}
+
+// Check validates the request against the fingerprint stored for the token.
+// Tokens created without fingerprint are never valid.
+func (f *Fingerprint) Check(c *yarf.Context, token string) bool {
+	stored, err := store.Get(fingerprintPrefix + token)
+	if err != nil {
+		return false
+	}
+
+	return f.Match(stored, f.Get(c))
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"github.com/yarf-framework/extras/context/data"
+	"github.com/yarf-framework/yarf"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newFingerprintContext(ip, ua string) *yarf.Context {
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.RemoteAddr = net.JoinHostPort(ip, "1234")
+	r.Header.Set("User-Agent", ua)
+
+	c := yarf.NewContext(r, httptest.NewRecorder())
+	c.Data = new(data.StrData)
+
+	return c
+}
+
+func TestFingerprintMatch(t *testing.T) {
+	f := &Fingerprint{IPv4Prefix: 24, IPv6Prefix: 64, UserAgent: true}
+
+	stored := f.Get(newFingerprintContext("192.168.1.10", "agent"))
+
+	if !f.Match(stored, f.Get(newFingerprintContext("192.168.1.20", "agent"))) {
+		t.Error("Address within the same /24 rejected")
+	}
+	if f.Match(stored, f.Get(newFingerprintContext("192.168.2.10", "agent"))) {
+		t.Error("Address out of the /24 accepted")
+	}
+	if f.Match(stored, f.Get(newFingerprintContext("192.168.1.10", "other"))) {
+		t.Error("Different User-Agent accepted")
+	}
+	if f.Match(stored, f.Get(newFingerprintContext("2001:db8::1", "agent"))) {
+		t.Error("Address family change accepted")
+	}
+
+	stored = f.Get(newFingerprintContext("2001:db8:0:1::1", "agent"))
+	if !f.Match(stored, f.Get(newFingerprintContext("2001:db8:0:1:ffff::1", "agent"))) {
+		t.Error("Address within the same /64 rejected")
+	}
+	if f.Match(stored, f.Get(newFingerprintContext("2001:db8:0:2::1", "agent"))) {
+		t.Error("Address out of the /64 accepted")
+	}
+}
+
+func TestFingerprintPrefixOutOfRange(t *testing.T) {
+	f := &Fingerprint{IPv4Prefix: 48, IPv6Prefix: 200}
+
+	if f.Match(f.Get(newFingerprintContext("10.0.0.1", "")), f.Get(newFingerprintContext("10.0.0.2", ""))) {
+		t.Error("IPv4 binding disabled by an out of range prefix")
+	}
+	if f.Match(f.Get(newFingerprintContext("2001:db8::1", "")), f.Get(newFingerprintContext("2001:db8::2", ""))) {
+		t.Error("IPv6 binding disabled by an out of range prefix")
+	}
+}
+
+func TestFingerprintMiddleware(t *testing.T) {
+	f := &Fingerprint{IPv4Prefix: 32}
+	a := &Auth{Fingerprint: f}
+
+	token := f.NewToken(newFingerprintContext("10.0.0.1", ""), "someid", 5)
+
+	c := newFingerprintContext("10.0.0.1", "")
+	c.Request.Header.Set("Auth", token)
+	if err := a.PreDispatch(c); err != nil {
+		t.Error(err.Error())
+	}
+
+	c = newFingerprintContext("10.0.0.2", "")
+	c.Request.Header.Set("Auth", token)
+	if err := a.PreDispatch(c); err == nil {
+		t.Error("Token accepted from a different client")
+	}
+
+	// Flag mode
+	f.Flag = true
+	if err := a.PreDispatch(c); err != nil {
+		t.Error(err.Error())
+	}
+	if v, _ := c.Data.Get("_authFingerprintMismatch"); v != "true" {
+		t.Error("Mismatch not flagged")
+	}
+}
+
+func TestFingerprintUnbound(t *testing.T) {
+	a := &Auth{Fingerprint: &Fingerprint{IPv4Prefix: 32}}
+
+	token := NewToken("someid", 5)
+	defer DeleteToken(token)
+
+	c := newFingerprintContext("10.0.0.1", "")
+	c.Request.Header.Set("Auth", token)
+	if err := a.PreDispatch(c); err == nil {
+		t.Error("Token without fingerprint accepted")
+	}
+}
+
+func TestFingerprintPendingToken(t *testing.T) {
+	f := &Fingerprint{IPv4Prefix: 32}
+	a := &Auth{Fingerprint: f}
+	secret := []byte("12345678901234567890")
+
+	token := f.NewPendingToken(newFingerprintContext("10.0.0.1", ""), "someid", 5)
+	defer DeleteToken(token)
+
+	if err := UpgradeToken(token, secret, DefaultTOTP.Generate(secret, time.Now()), 5); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	c := newFingerprintContext("10.0.0.1", "")
+	c.Request.Header.Set("Auth", token)
+	if err := a.PreDispatch(c); err != nil {
+		t.Error(err.Error())
+	}
+
+	c = newFingerprintContext("10.0.0.2", "")
+	c.Request.Header.Set("Auth", token)
+	if err := a.PreDispatch(c); err == nil {
+		t.Error("Upgraded token accepted from a different client")
+	}
+}
@@ -1,8 +1,8 @@
 package auth
 
 import (
+	"context"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
@@ -30,50 +30,122 @@ type authToken struct {
 	expiration time.Time // Expiration time calculated after duration
 }
 
-// authStorage is the internal implementation for Storage interface.
-// It uses a in-memory map to store auth data.
-type authStorage struct {
+// MemoryStorage is the default implementation of the Storage interface.
+// It uses a in-memory map to store auth data. Create it with NewMemoryStorage.
+type MemoryStorage struct {
 	// Data store
 	store map[string]authToken
 
-	// Garbage collector running?
-	gcFlag int64
+	// Garbage collector sweep interval
+	interval time.Duration
+
+	// Garbage collector parent context
+	ctx context.Context
+
+	// Garbage collector cancel function
+	cancel context.CancelFunc
+
+	// Closed when the garbage collector stops
+	done chan struct{}
+
+	// Set after Close, prevents the garbage collector from starting again
+	closed bool
 
 	// Sync Mutex
 	sync.RWMutex
 }
 
-// authStorage's garbage collector
-func (as *authStorage) gc() {
-	// Set running flag
-	atomic.StoreInt64(&as.gcFlag, 1)
+// NewMemoryStorage creates the in-memory Storage used by default.
+// Expired tokens are removed every interval (1 minute if interval <= 0) by a garbage collector
+// that starts on first use and stops when ctx is cancelled or the storage is closed.
+func NewMemoryStorage(ctx context.Context, interval time.Duration) *MemoryStorage {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	return &MemoryStorage{
+		store:    make(map[string]authToken),
+		interval: interval,
+		ctx:      ctx,
+	}
+}
+
+// startGC runs the garbage collector if it isn't running yet.
+// It must be called holding the write lock.
+func (as *MemoryStorage) startGC() {
+	if as.done != nil || as.closed {
+		return
+	}
+
+	ctx := as.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if as.interval <= 0 {
+		as.interval = time.Minute
+	}
+
+	ctx, as.cancel = context.WithCancel(ctx)
+	as.done = make(chan struct{})
+
+	go as.gc(ctx, as.done, as.interval)
+}
+
+// MemoryStorage's garbage collector
+func (as *MemoryStorage) gc(ctx context.Context, done chan struct{}, interval time.Duration) {
+	defer close(done)
 
-	// Run every minute.
-	t := time.NewTicker(1 * time.Minute)
+	t := time.NewTicker(interval)
+	defer t.Stop()
 
-	for _ = range t.C {
-		// Cancel when storage not present
-		if as.store == nil {
-			t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
 			return
+
+		case <-t.C:
+			as.Sweep()
 		}
+	}
+}
+
+// Sweep removes all expired entries from the storage.
+// It's called periodically by the garbage collector and can be called directly.
+func (as *MemoryStorage) Sweep() {
+	now := time.Now()
 
-		// Check for expired storage entries.
-		now := time.Now()
+	// Full lock during GC
+	as.Lock()
+	defer as.Unlock()
 
-		// Full lock during GC
-		as.Lock()
-		for key, data := range as.store {
-			if now.After(data.expiration) {
-				delete(as.store, key)
-			}
+	for key, data := range as.store {
+		if now.After(data.expiration) {
+			delete(as.store, key)
 		}
-		as.Unlock()
 	}
 }
 
+// Close stops the garbage collector and waits for it to return.
+// Stored data is kept and still accessible, but expired entries won't be removed anymore.
+func (as *MemoryStorage) Close() error {
+	as.Lock()
+	as.closed = true
+	cancel, done := as.cancel, as.done
+	as.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	return nil
+}
+
 // Get data from storage
-func (as *authStorage) Get(key string) (string, error) {
+func (as *MemoryStorage) Get(key string) (string, error) {
 	as.RLock()
 	defer as.RUnlock()
 
@@ -89,7 +161,7 @@ func (as *authStorage) Get(key string) (string, error) {
 }
 
 // Set data to storage.
-func (as *authStorage) Set(key, data string, duration int) error {
+func (as *MemoryStorage) Set(key, data string, duration int) error {
 	// Calculate expiration time
 	exp := time.Now().Add(time.Duration(duration) * time.Second)
 
@@ -101,15 +173,13 @@ func (as *authStorage) Set(key, data string, duration int) error {
 
 	// Init GC if not running yet.
 	// Write lock comes handy here.
-	if atomic.LoadInt64(&as.gcFlag) == 0 {
-		go as.gc()
-	}
+	as.startGC()
 
 	return nil
 }
 
 // Refresh expiration
-func (as *authStorage) Refresh(key string) error {
+func (as *MemoryStorage) Refresh(key string) error {
 	as.Lock()
 	defer as.Unlock()
 
@@ -125,7 +195,7 @@ func (as *authStorage) Refresh(key string) error {
 }
 
 // Delete data to storage.
-func (as *authStorage) Del(key string) error {
+func (as *MemoryStorage) Del(key string) error {
 	as.Lock()
 	defer as.Unlock()
 
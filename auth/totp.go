@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// pendingPrefix namespaces tokens waiting for the second factor inside the storage.
+	pendingPrefix = "_pending:"
+
+	// totpUsedPrefix namespaces the used TOTP codes inside the storage.
+	totpUsedPrefix = "_totp:"
+)
+
+// TOTP holds the RFC 6238 time-based one-time password settings.
+type TOTP struct {
+	// Amount of digits of each code.
+	Digits int
+
+	// Time step in seconds. Defaults to 30 when not set.
+	Period int
+
+	// Amount of steps accepted before and after the current one, to allow clock drift.
+	Skew int
+
+	// Sync Mutex used to check and mark used codes.
+	sync.Mutex
+}
+
+// DefaultTOTP is used by UpgradeToken.
+// Its values match the ones used by most authenticator apps: 6 digits, 30 seconds step and 1 step of skew.
+var DefaultTOTP = &TOTP{
+	Digits: 6,
+	Period: 30,
+	Skew:   1,
+}
+
+// Generate returns the code for a secret at a given time.
+func (t *TOTP) Generate(secret []byte, at time.Time) string {
+	return t.code(secret, t.step(at))
+}
+
+// Validate checks a code against the secret for the actual time within the configured skew window.
+// A code is accepted only once: after a successful validation, the same code for the same secret will fail
+// until it gets out of the skew window.
+// It returns an InvalidCodeError when the code isn't valid or has been used already.
+func (t *TOTP) Validate(secret []byte, code string) error {
+	if len(code) != t.Digits {
+		return InvalidCodeError{}
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	now := t.step(time.Now())
+	for i := -t.Skew; i <= t.Skew; i++ {
+		s := now + int64(i)
+		if subtle.ConstantTimeCompare([]byte(t.code(secret, s)), []byte(code)) != 1 {
+			continue
+		}
+
+		// Replay check
+		key := t.usedKey(secret, s)
+		if _, err := store.Get(key); err == nil {
+			return InvalidCodeError{}
+		}
+
+		// Keep it while the code is still accepted.
+		store.Set(key, code, t.period()*(2*t.Skew+2))
+
+		return nil
+	}
+
+	return InvalidCodeError{}
+}
+
+// period returns the time step in seconds, 30 when it's not set.
+func (t *TOTP) period() int {
+	if t.Period <= 0 {
+		return 30
+	}
+
+	return t.Period
+}
+
+// step calculates the RFC 6238 time counter.
+func (t *TOTP) step(at time.Time) int64 {
+	return at.Unix() / int64(t.period())
+}
+
+// code calculates the RFC 4226 HOTP value for a counter.
+func (t *TOTP) code(secret []byte, counter int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := int64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+
+	mod := int64(1)
+	for i := 0; i < t.Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0"+strconv.Itoa(t.Digits)+"d", value%mod)
+}
+
+// usedKey returns the storage key used to mark a code as used, without exposing the secret.
+func (t *TOTP) usedKey(secret []byte, counter int64) string {
+	return fmt.Sprintf("%s%x:%d", totpUsedPrefix, sha256.Sum256(secret), counter)
+}
+
+// NewPendingToken creates and stores a new token that is authenticated but waits for a second factor.
+// Pending tokens aren't accepted by ValidateToken nor by the Auth middleware until they get upgraded by UpgradeToken.
+// It should be used from a Login method after a successful password authentication of accounts with 2FA enabled.
+func NewPendingToken(data string, d int) string {
+	t := generateToken()
+
+	// Check non-existence of the new token
+	_, check := store.Get(pendingPrefix + t)
+	for check == nil {
+		t = generateToken()
+		_, check = store.Get(pendingPrefix + t)
+	}
+
+	// Save data
+	store.Set(pendingPrefix+t, data, d)
+
+	return t
+}
+
+// IsPending returns true when the token is valid but still waits for the second factor.
+func IsPending(token string) bool {
+	_, err := store.Get(pendingPrefix + token)
+
+	return err == nil
+}
+
+// UpgradeToken validates a TOTP code using DefaultTOTP and, on success, turns the pending token into a full token
+// valid for d seconds. The token value doesn't change, so clients can keep using it.
+// It returns an InvalidKeyError if the token isn't pending, or an InvalidCodeError if the code isn't valid.
+func UpgradeToken(token string, secret []byte, code string, d int) error {
+	data, err := store.Get(pendingPrefix + token)
+	if err != nil {
+		return err
+	}
+
+	err = DefaultTOTP.Validate(secret, code)
+	if err != nil {
+		return err
+	}
+
+	// Promote
+	store.Del(pendingPrefix + token)
+
+	// Keep the fingerprint, if any, for the new duration.
+	if fp, err := store.Get(fingerprintPrefix + token); err == nil {
+		store.Set(fingerprintPrefix+token, fp, d)
+	}
+
+	return store.Set(token, data, d)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTOTPGenerate(t *testing.T) {
+	// RFC 6238 Appendix B test vectors for SHA1
+	secret := []byte("12345678901234567890")
+	totp := &TOTP{Digits: 8, Period: 30}
+
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+
+	for ts, code := range vectors {
+		if c := totp.Generate(secret, time.Unix(ts, 0)); c != code {
+			t.Errorf("Code for %d: expected %s, got %s", ts, code, c)
+		}
+	}
+}
+
+func TestTOTPDefaultPeriod(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	at := time.Unix(1111111109, 0)
+
+	if c := (&TOTP{Digits: 8}).Generate(secret, at); c != "07081804" {
+		t.Errorf("Expected the 30 seconds step code, got %s", c)
+	}
+
+	// Used codes are kept for the default period too.
+	totp := &TOTP{Digits: 6, Skew: 1}
+	secret = []byte("default-period-secret")
+	code := totp.Generate(secret, time.Now())
+	if err := totp.Validate(secret, code); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := totp.Validate(secret, code); err == nil {
+		t.Error("Code accepted twice")
+	}
+}
+
+func TestTOTPValidate(t *testing.T) {
+	secret := []byte("validate-secret")
+	totp := &TOTP{Digits: 6, Period: 30, Skew: 1}
+
+	// Previous step is inside the skew window
+	code := totp.Generate(secret, time.Now().Add(-30*time.Second))
+	if err := totp.Validate(secret, code); err != nil {
+		t.Error(err.Error())
+	}
+
+	// Replay
+	if err := totp.Validate(secret, code); err == nil {
+		t.Error("Code accepted twice")
+	}
+
+	// Out of window
+	code = totp.Generate(secret, time.Now().Add(-90*time.Second))
+	if err := totp.Validate(secret, code); err == nil {
+		t.Error("Code out of skew window accepted")
+	}
+}
+
+func TestUpgradeToken(t *testing.T) {
+	secret := []byte("upgrade-secret")
+	token := NewPendingToken("someid", 5)
+
+	if _, err := ValidateToken(token); err == nil {
+		t.Error("Pending token accepted before second factor")
+	}
+	if _, err := ValidateToken(pendingPrefix + token); err == nil {
+		t.Error("Pending token accepted using the storage key")
+	}
+
+	if err := UpgradeToken(token, secret, "000000x", 5); err == nil {
+		t.Error("Invalid code accepted")
+	}
+
+	code := DefaultTOTP.Generate(secret, time.Now())
+	if err := UpgradeToken(token, secret, code, 5); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	data, err := ValidateToken(token)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if data != "someid" {
+		t.Error("Token data missmatch")
+	}
+	if IsPending(token) {
+		t.Error("Token still pending after upgrade")
+	}
+}
+
+func TestPendingTokenMiddleware(t *testing.T) {
+	a := new(Auth)
+
+	token := NewPendingToken("someid", 5)
+	defer store.Del(pendingPrefix + token)
+
+	c := newFingerprintContext("10.0.0.1", "")
+	c.Request.Header.Set("Auth", token)
+	if _, ok := a.PreDispatch(c).(*SecondFactorError); !ok {
+		t.Error("Pending token didn't return a SecondFactorError")
+	}
+	if v, _ := c.Data.Get("_authData"); v != "" {
+		t.Error("Pending token data set")
+	}
+}
@@ -27,3 +27,31 @@ func (e *UnauthorizedError) Msg() string {
 func (e *UnauthorizedError) Body() string {
 	return "Unauthorized"
 }
+
+// SecondFactorError is returned by the Auth middleware when the token is still waiting for the second factor.
+type SecondFactorError struct{}
+
+// Implements the error interface returning the ErrorMsg value of each error.
+func (e *SecondFactorError) Error() string {
+	return "Second factor required"
+}
+
+// Code returns the error's HTTP code to be used in the response.
+func (e *SecondFactorError) Code() int {
+	return 401
+}
+
+// ID returns the error's ID for further reference.
+func (e *SecondFactorError) ID() int {
+	return 401
+}
+
+// Msg returns the error's message, used to implement the Error interface.
+func (e *SecondFactorError) Msg() string {
+	return "Second factor required"
+}
+
+// Body returns the error's content body, if needed, to be returned in the HTTP response.
+func (e *SecondFactorError) Body() string {
+	return "Second factor required"
+}
@@ -8,19 +8,44 @@ import (
 // It also provides methods to generate and validate the tokens, that can be used by clients to perform authentication and authorization.
 type Auth struct {
 	yarf.Middleware
+
+	// Optional token binding to the client. See Fingerprint.
+	Fingerprint *Fingerprint
+
+	// Optional encrypted cookie sessions. When set, tokens are validated by the CookieStore instead of the storage.
+	Cookies *CookieStore
 }
 
 // PreDispatch checks if a token has been sent on the request, either by cookie or Auth header.
 // If the token is invalid or non-present, it will return an error to stop execution of the following resources.
+// When a Fingerprint is set, tokens used from a different client are rejected or flagged.
+// Tokens still waiting for the second factor are rejected with a SecondFactorError.
 // If a token is valid, it returns its data on the "Auth" index of the yarf.Context.Data object.
 func (a *Auth) PreDispatch(c *yarf.Context) error {
 	token := GetToken(c.Request)
 
+	if a.Cookies != nil {
+		return a.cookieDispatch(c, token)
+	}
+
 	data, err := ValidateToken(token)
 	if err != nil {
+		if IsPending(token) {
+			return new(SecondFactorError)
+		}
+
 		return new(UnauthorizedError)
 	}
 
+	// Check client binding
+	if a.Fingerprint != nil && !a.Fingerprint.Check(c, token) {
+		if !a.Fingerprint.Flag {
+			return new(UnauthorizedError)
+		}
+
+		c.Data.Set("_authFingerprintMismatch", "true")
+	}
+
 	c.Data.Set("_authData", data)
 	c.Data.Set("_authToken", token)
 
@@ -29,3 +54,23 @@ func (a *Auth) PreDispatch(c *yarf.Context) error {
 
 	return nil
 }
+
+// cookieDispatch validates a CookieStore token and sets a refreshed cookie on the response.
+func (a *Auth) cookieDispatch(c *yarf.Context, token string) error {
+	data, d, err := a.Cookies.open(token)
+	if err != nil {
+		return new(UnauthorizedError)
+	}
+
+	// Refresh token expiration on every request.
+	token, err = a.Cookies.NewToken(data, d)
+	if err != nil {
+		return err
+	}
+	a.Cookies.SetCookie(c.Response, token, d)
+
+	c.Data.Set("_authData", data)
+	c.Data.Set("_authToken", token)
+
+	return nil
+}
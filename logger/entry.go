@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"github.com/yarf-framework/extras/realip"
+	"github.com/yarf-framework/yarf"
+	"time"
+)
+
+// Entry holds the values logged for a request.
+type Entry struct {
+	// Time the request was received
+	Time time.Time
+
+	// Client IP address, as returned by realip.Get
+	IP string
+
+	// Request method
+	Method string
+
+	// Request path
+	Path string
+
+	// Request raw query, without "?"
+	Query string
+
+	// Response status code
+	Status int
+
+	// Time taken to handle the request
+	Latency time.Duration
+
+	// Response body bytes
+	Bytes int64
+
+	// User-Agent request header
+	UserAgent string
+
+	// Referer request header
+	Referer string
+
+	// Request ID, from the Logger's RequestIDHeader
+	RequestID string
+
+	// Auth subject, from the "_authData" context data set by the auth middleware
+	User string
+}
+
+// entry collects the values of a request at its end.
+func (l *Logger) entry(c *yarf.Context) *Entry {
+	e := &Entry{
+		Time:      time.Now(),
+		IP:        realip.Get(c),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Query:     c.Request.URL.RawQuery,
+		UserAgent: c.Request.UserAgent(),
+		Referer:   c.Request.Referer(),
+	}
+
+	if lw, ok := GetWriter(c); ok {
+		e.Status = lw.StatusCode
+		e.Bytes = lw.Bytes
+
+		if !lw.Start.IsZero() {
+			e.Latency = e.Time.Sub(lw.Start)
+			e.Time = lw.Start
+		}
+	}
+
+	// If nobody writes the response, it's an empty 200
+	if e.Status == 0 {
+		e.Status = 200
+	}
+
+	header := l.RequestIDHeader
+	if header == "" {
+		header = "X-Request-ID"
+	}
+	e.RequestID = c.Request.Header.Get(header)
+
+	if c.Data != nil {
+		if data, err := c.Data.Get("_authData"); err == nil {
+			e.User, _ = data.(string)
+		}
+	}
+
+	return e
+}
@@ -0,0 +1,272 @@
+package logger
+
+import (
+	"fmt"
+	"github.com/yarf-framework/yarf"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Common and Combined are the Apache Common Log Format and Combined Log Format strings.
+const (
+	Common   = `%h %l %u %t "%r" %>s %b`
+	Combined = Common + ` "%{Referer}i" "%{User-Agent}i"`
+)
+
+// Format is a compiled log format string.
+// Formats are compiled once, so logging a request doesn't parse them again.
+//
+// Supported directives, like Apache's mod_log_config:
+//
+//	%h        Client IP address
+//	%l        Remote logname, always "-"
+//	%u        Auth subject from the "_authData" context data, or "-"
+//	%t        Request time, like [10/Oct/2000:13:55:36 -0700]
+//	%r        Request line, like GET /items?page=2 HTTP/1.1
+//	%s, %>s   Response status code
+//	%b        Response body bytes, or "-" when empty
+//	%B        Response body bytes
+//	%D        Time taken in microseconds
+//	%T        Time taken in seconds
+//	%m        Request method
+//	%U        Request path
+//	%q        Request query, with its "?", or empty
+//	%H        Request protocol
+//	%{Name}i  Request header
+//	%{Name}o  Response header
+//	%{key}d   Context data value
+//	%%        A "%" sign
+type Format struct {
+	// Source format string
+	source string
+
+	// Compiled directives and literals
+	parts []part
+}
+
+// part appends a directive or literal of a Format to a log line.
+type part func(b []byte, c *yarf.Context, e *Entry) []byte
+
+// Compile parses a log format string.
+func Compile(format string) (*Format, error) {
+	f := &Format{source: format}
+
+	for i := 0; i < len(format); i++ {
+		// Literal
+		j := strings.IndexByte(format[i:], '%')
+		if j != 0 {
+			if j < 0 {
+				j = len(format) - i
+			}
+			f.parts = append(f.parts, literal(format[i:i+j]))
+			i += j - 1
+			continue
+		}
+
+		// Directive
+		i++
+		var arg string
+		if i < len(format) && format[i] == '{' {
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("logger: unclosed { in format %q", format)
+			}
+			arg = format[i+1 : i+end]
+			i += end + 1
+		}
+		if i < len(format) && (format[i] == '>' || format[i] == '<') {
+			i++
+		}
+		if i >= len(format) {
+			return nil, fmt.Errorf("logger: incomplete directive at the end of format %q", format)
+		}
+
+		p, err := directive(format[i], arg)
+		if err != nil {
+			return nil, err
+		}
+		f.parts = append(f.parts, p)
+	}
+
+	return f, nil
+}
+
+// MustCompile is like Compile but panics if the format can't be parsed.
+// It's meant to initialize global variables and Logger options.
+func MustCompile(format string) *Format {
+	f, err := Compile(format)
+	if err != nil {
+		panic(err)
+	}
+
+	return f
+}
+
+// String returns the source format string.
+func (f *Format) String() string {
+	return f.source
+}
+
+// Append appends the log line of a request, without line break, to b.
+func (f *Format) Append(b []byte, c *yarf.Context, e *Entry) []byte {
+	for _, p := range f.parts {
+		b = p(b, c, e)
+	}
+
+	return b
+}
+
+// literal returns the part appending a fixed text.
+func literal(s string) part {
+	return func(b []byte, c *yarf.Context, e *Entry) []byte {
+		return append(b, s...)
+	}
+}
+
+// directive returns the part for a directive letter and its {argument}.
+func directive(d byte, arg string) (part, error) {
+	switch d {
+	case 'h':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return appendValue(b, e.IP)
+		}, nil
+
+	case 'l':
+		return literal("-"), nil
+
+	case 'u':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return appendValue(b, e.User)
+		}, nil
+
+	case 't':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			b = append(b, '[')
+			b = e.Time.AppendFormat(b, "02/Jan/2006:15:04:05 -0700")
+			return append(b, ']')
+		}, nil
+
+	case 'r':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return appendEscaped(b, c.Request.Method+" "+c.Request.URL.RequestURI()+" "+c.Request.Proto)
+		}, nil
+
+	case 's':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return strconv.AppendInt(b, int64(e.Status), 10)
+		}, nil
+
+	case 'b':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			if e.Bytes == 0 {
+				return append(b, '-')
+			}
+			return strconv.AppendInt(b, e.Bytes, 10)
+		}, nil
+
+	case 'B':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return strconv.AppendInt(b, e.Bytes, 10)
+		}, nil
+
+	case 'D':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return strconv.AppendInt(b, int64(e.Latency/time.Microsecond), 10)
+		}, nil
+
+	case 'T':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return strconv.AppendInt(b, int64(e.Latency/time.Second), 10)
+		}, nil
+
+	case 'm':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return appendEscaped(b, e.Method)
+		}, nil
+
+	case 'U':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return appendEscaped(b, e.Path)
+		}, nil
+
+	case 'q':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			if e.Query == "" {
+				return b
+			}
+			return appendEscaped(append(b, '?'), e.Query)
+		}, nil
+
+	case 'H':
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return appendEscaped(b, c.Request.Proto)
+		}, nil
+
+	case 'i':
+		if arg == "" {
+			break
+		}
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return appendValue(b, c.Request.Header.Get(arg))
+		}, nil
+
+	case 'o':
+		if arg == "" {
+			break
+		}
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			return appendValue(b, c.Response.Header().Get(arg))
+		}, nil
+
+	case 'd':
+		if arg == "" {
+			break
+		}
+		return func(b []byte, c *yarf.Context, e *Entry) []byte {
+			var s string
+			if c.Data != nil {
+				v, _ := c.Data.Get(arg)
+				s, _ = v.(string)
+			}
+			return appendValue(b, s)
+		}, nil
+
+	case '%':
+		return literal("%"), nil
+	}
+
+	if arg != "" {
+		return nil, fmt.Errorf("logger: unknown directive %%{%s}%c", arg, d)
+	}
+
+	return nil, fmt.Errorf("logger: unknown directive %%%c", d)
+}
+
+// appendValue appends an escaped value, or "-" if it's empty.
+func appendValue(b []byte, s string) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+
+	return appendEscaped(b, s)
+}
+
+// appendEscaped appends a value escaping quotes, backslashes and control characters,
+// so request values can't break or forge log lines.
+func appendEscaped(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '"' || ch == '\\':
+			b = append(b, '\\', ch)
+
+		case ch < 0x20 || ch == 0x7f:
+			b = append(b, fmt.Sprintf("\\x%02x", ch)...)
+
+		default:
+			b = append(b, ch)
+		}
+	}
+
+	return b
+}
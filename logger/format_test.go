@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"bytes"
+	"github.com/yarf-framework/extras/context/data"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompile(t *testing.T) {
+	for _, f := range []string{Common, Combined, "%D %T %m %U%q %H %{X-Trace}o %{_subdomain}d %B 100%%"} {
+		if _, err := Compile(f); err != nil {
+			t.Errorf("%s: %s", f, err.Error())
+		}
+	}
+
+	for _, f := range []string{"%z", "%{Referer", "%{Referer}", "%", "%{key}z"} {
+		if _, err := Compile(f); err == nil {
+			t.Errorf("Invalid format %q compiled", f)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	start := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600))
+	e := &Entry{
+		Time:    start,
+		IP:      "10.0.0.1",
+		Method:  "GET",
+		Path:    "/items",
+		Query:   "page=2",
+		Status:  200,
+		Latency: 1500 * time.Microsecond,
+		Bytes:   2326,
+		User:    "frank",
+	}
+
+	c := newTestContext("GET", "/items?page=2")
+	c.Request.Header.Set("Referer", "http://example.com/")
+	c.Request.Header.Set("User-Agent", `Mozilla "quoted"`)
+	c.Response.Header().Set("X-Trace", "t1")
+	c.Data = new(data.StrData)
+	c.Data.Set("_subdomain", "api")
+
+	for format, line := range map[string]string{
+		Common:   `10.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /items?page=2 HTTP/1.1" 200 2326`,
+		Combined: `10.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /items?page=2 HTTP/1.1" 200 2326 "http://example.com/" "Mozilla \"quoted\""`,
+		"%D %T %m %U%q %H %{X-Trace}o %{_subdomain}d %{Missing}i 100%%": "1500 0 GET /items?page=2 HTTP/1.1 t1 api - 100%",
+	} {
+		if s := string(MustCompile(format).Append(nil, c, e)); s != line {
+			t.Errorf("%s:\nexpected %s\ngot      %s", format, line, s)
+		}
+	}
+
+	// Empty values
+	e.Bytes, e.User = 0, ""
+	if s := string(MustCompile("%u %b %B").Append(nil, c, e)); s != "- - 0" {
+		t.Errorf("Unexpected empty values %s", s)
+	}
+}
+
+func TestLoggerFormat(t *testing.T) {
+	var out bytes.Buffer
+	l := &Logger{Format: MustCompile(Combined), Output: &out}
+
+	c := newTestContext("GET", "/")
+	c.Request.Header["User-Agent"] = []string{"ua\n\"forged\""}
+	l.PreDispatch(c)
+	c.Response.Write([]byte("ok"))
+	l.End(c)
+
+	s := out.String()
+	if strings.Count(s, "\n") != 1 || !strings.HasPrefix(s, "10.0.0.1 - - [") || !strings.HasSuffix(s, `" 200 2 "-" "ua\x0a\"forged\""`+"\n") {
+		t.Errorf("Unexpected line %q", s)
+	}
+}
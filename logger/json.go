@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// JSON fields, named after their key on the logged objects.
+const (
+	FieldTime      = "time"
+	FieldIP        = "ip"
+	FieldMethod    = "method"
+	FieldPath      = "path"
+	FieldQuery     = "query"
+	FieldStatus    = "status"
+	FieldLatency   = "latency_ms"
+	FieldBytes     = "bytes"
+	FieldUserAgent = "user_agent"
+	FieldReferer   = "referer"
+	FieldRequestID = "request_id"
+	FieldUser      = "user"
+)
+
+// DefaultFields are the fields logged in JSON mode when no Fields are set.
+var DefaultFields = []string{
+	FieldTime, FieldIP, FieldMethod, FieldPath, FieldQuery, FieldStatus, FieldLatency,
+	FieldBytes, FieldUserAgent, FieldReferer, FieldRequestID, FieldUser,
+}
+
+// value returns the value of a field, or nil for unknown fields.
+func (e *Entry) value(field string) interface{} {
+	switch field {
+	case FieldTime:
+		return e.Time.Format(time.RFC3339Nano)
+	case FieldIP:
+		return e.IP
+	case FieldMethod:
+		return e.Method
+	case FieldPath:
+		return e.Path
+	case FieldQuery:
+		return e.Query
+	case FieldStatus:
+		return e.Status
+	case FieldLatency:
+		return float64(e.Latency) / float64(time.Millisecond)
+	case FieldBytes:
+		return e.Bytes
+	case FieldUserAgent:
+		return e.UserAgent
+	case FieldReferer:
+		return e.Referer
+	case FieldRequestID:
+		return e.RequestID
+	case FieldUser:
+		return e.User
+	}
+
+	return nil
+}
+
+// JSON encodes the given fields of the entry as a JSON object on a single line, keeping the fields order.
+// Unknown fields are skipped.
+func (e *Entry) JSON(fields []string) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for _, f := range fields {
+		v := e.value(f)
+		if v == nil {
+			continue
+		}
+
+		// Values are strings and numbers, they can't fail.
+		k, _ := json.Marshal(f)
+		b, _ := json.Marshal(v)
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(b)
+	}
+	buf.WriteString("}\n")
+
+	return buf.Bytes()
+}
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/yarf-framework/extras/context/data"
+	"github.com/yarf-framework/yarf"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func newTestContext(method, url string) *yarf.Context {
+	r, _ := http.NewRequest(method, url, nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+
+	return yarf.NewContext(r, httptest.NewRecorder())
+}
+
+func TestLine(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.LstdFlags)
+
+	l := new(Logger)
+	c := newTestContext("GET", "/items?page=2")
+	l.PreDispatch(c)
+	c.Response.Write([]byte("ok"))
+	l.End(c)
+
+	if s := out.String(); s != "| 10.0.0.1 | GET | 200 | /items?page=2\n" {
+		t.Errorf("Unexpected line %q", s)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var out bytes.Buffer
+	l := &Logger{JSON: true, Output: &out}
+
+	c := newTestContext("POST", "/items?page=2")
+	c.Request.Header.Set("User-Agent", "test")
+	c.Request.Header.Set("X-Request-ID", "abc")
+	c.Data = new(data.StrData)
+	c.Data.Set("_authData", "user-1")
+
+	l.PreDispatch(c)
+	c.Response.WriteHeader(201)
+	c.Response.Write([]byte("created"))
+	l.End(c)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for k, v := range map[string]interface{}{
+		"ip":         "10.0.0.1",
+		"method":     "POST",
+		"path":       "/items",
+		"query":      "page=2",
+		"status":     float64(201),
+		"bytes":      float64(7),
+		"user_agent": "test",
+		"request_id": "abc",
+		"user":       "user-1",
+	} {
+		if entry[k] != v {
+			t.Errorf("Expected %s %v, got %v", k, v, entry[k])
+		}
+	}
+	if _, ok := entry["latency_ms"]; !ok {
+		t.Error("Missing latency")
+	}
+}
+
+func TestJSONFields(t *testing.T) {
+	var out bytes.Buffer
+	l := &Logger{JSON: true, Output: &out, Fields: []string{FieldStatus, FieldPath, "unknown"}}
+
+	c := newTestContext("GET", "/")
+	l.PreDispatch(c)
+	l.End(c)
+
+	if s := out.String(); s != "{\"status\":200,\"path\":\"/\"}\n" {
+		t.Errorf("Unexpected line %s", s)
+	}
+}
@@ -1,13 +1,46 @@
 package logger
 
 import (
+	"github.com/yarf-framework/yarf"
 	"net/http"
+	"time"
 )
 
 // LoggerWriter will replace (wrap) the http.ResponseWriter to log all content written to the response.
+// Other middleware can read its values through GetWriter.
 type LoggerWriter struct {
+	// Response status code, 0 until the headers are written.
 	StatusCode int
-	Writer     http.ResponseWriter
+
+	Writer http.ResponseWriter
+
+	// Request start time, set by the Logger middleware on PreDispatch.
+	Start time.Time
+
+	// Body bytes written to the response.
+	Bytes int64
+
+	// Set when the headers were written implicitly with a 200 status, by a Write without WriteHeader.
+	Implicit bool
+}
+
+// GetWriter returns the LoggerWriter set by the Logger middleware on the request,
+// even if other middleware wrapped the response again, as long as their writers implement Unwrap.
+func GetWriter(c *yarf.Context) (*LoggerWriter, bool) {
+	w := c.Response
+	for w != nil {
+		if lw, ok := w.(*LoggerWriter); ok {
+			return lw, true
+		}
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+
+	return nil, false
 }
 
 // Header is a wrapper for http.ResponseWriter.Header()
@@ -17,13 +50,45 @@ func (lw *LoggerWriter) Header() http.Header {
 
 // WriteHeader is a wrapper for http.ResponseWriter.WriteHeader()
 // It saves the status code to be returned so we can log it.
+// As with http.ResponseWriter, only the first final (not 1xx) status code is sent.
 func (lw *LoggerWriter) WriteHeader(code int) {
-	lw.StatusCode = code
+	if lw.StatusCode == 0 && code >= 200 {
+		lw.StatusCode = code
+	}
 
 	lw.Writer.WriteHeader(code)
 }
 
-// Write is a wrapper for
+// Write is a wrapper for http.ResponseWriter.Write()
+// It counts the bytes written so we can log them, and records the implicit 200 status
+// when the headers weren't written yet.
 func (lw *LoggerWriter) Write(content []byte) (int, error) {
-	return lw.Writer.Write(content)
+	if lw.StatusCode == 0 {
+		lw.StatusCode = 200
+		lw.Implicit = true
+	}
+
+	n, err := lw.Writer.Write(content)
+	lw.Bytes += int64(n)
+
+	return n, err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, so http.ResponseController can reach its features, like Flush.
+func (lw *LoggerWriter) Unwrap() http.ResponseWriter {
+	return lw.Writer
+}
+
+// Written reports if the headers have been written, explicitly or implicitly.
+func (lw *LoggerWriter) Written() bool {
+	return lw.StatusCode != 0
+}
+
+// Elapsed returns the time since the request start.
+func (lw *LoggerWriter) Elapsed() time.Duration {
+	if lw.Start.IsZero() {
+		return 0
+	}
+
+	return time.Since(lw.Start)
 }
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// wrapper is a response writer set by another middleware after the Logger.
+type wrapper struct {
+	http.ResponseWriter
+}
+
+func (w *wrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestLoggerWriter(t *testing.T) {
+	c := newTestContext("GET", "/")
+	new(Logger).PreDispatch(c)
+	c.Response = &wrapper{c.Response}
+
+	lw, ok := GetWriter(c)
+	if !ok {
+		t.Fatal("LoggerWriter not found")
+	}
+	if lw.Start.IsZero() || lw.Written() {
+		t.Error("Unexpected initial state")
+	}
+
+	// Implicit headers
+	c.Response.Write([]byte("hello"))
+	c.Response.WriteHeader(500)
+	c.Response.Write([]byte(" world"))
+
+	if lw.StatusCode != 200 || !lw.Implicit || !lw.Written() {
+		t.Errorf("Expected implicit 200, got %d", lw.StatusCode)
+	}
+	if lw.Bytes != 11 {
+		t.Errorf("Expected 11 bytes, got %d", lw.Bytes)
+	}
+	if lw.Elapsed() <= 0 {
+		t.Error("Elapsed time not measured")
+	}
+
+	if _, ok := GetWriter(newTestContext("GET", "/")); ok {
+		t.Error("LoggerWriter found without Logger")
+	}
+}
+
+func TestLoggerWriterStatus(t *testing.T) {
+	c := newTestContext("GET", "/")
+	new(Logger).PreDispatch(c)
+	lw, _ := GetWriter(c)
+
+	c.Response.WriteHeader(103)
+	c.Response.WriteHeader(404)
+	c.Response.Write([]byte("not found"))
+
+	if lw.StatusCode != 404 || lw.Implicit {
+		t.Errorf("Expected explicit 404, got %d", lw.StatusCode)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	var out bytes.Buffer
+	l := &Logger{JSON: true, Output: &out, Fields: []string{FieldStatus, FieldLatency}}
+
+	c := newTestContext("GET", "/")
+	l.PreDispatch(c)
+	lw, _ := GetWriter(c)
+	lw.Start = lw.Start.Add(-50 * time.Millisecond)
+	c.Response.Write([]byte("ok"))
+	l.End(c)
+
+	var entry map[string]float64
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatal(err.Error())
+	}
+	if entry["status"] != 200 || entry["latency_ms"] < 50 {
+		t.Errorf("Unexpected entry %s", out.String())
+	}
+}
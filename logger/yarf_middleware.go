@@ -2,7 +2,10 @@ package logger
 
 import (
 	"github.com/yarf-framework/yarf"
+	"io"
 	"log"
+	"sync"
+	"time"
 )
 
 // Logger middleware it's a simple log module that uses the default golang's log package.
@@ -11,6 +14,26 @@ import (
 // a custom solution to replace this should be implemented.
 type Logger struct {
 	yarf.Middleware
+
+	// Logs a JSON object per request, for log pipelines, instead of the default line.
+	JSON bool
+
+	// Logs a line per request using a compiled format, like MustCompile(Combined), instead of the default line.
+	// JSON takes precedence over Format.
+	Format *Format
+
+	// Fields of the JSON objects, in order. Defaults to DefaultFields.
+	Fields []string
+
+	// Request header carrying the request ID. Defaults to "X-Request-ID".
+	RequestIDHeader string
+
+	// Destination of the JSON objects and Format lines.
+	// Defaults to the output of the default golang's log package, without its prefix.
+	Output io.Writer
+
+	// Sync Mutex, so concurrent requests don't mix their lines on Output.
+	sync.Mutex
 }
 
 // PreDispatch wraps the http.ResponseWriter with a new LoggerWritter
@@ -18,29 +41,56 @@ type Logger struct {
 func (l *Logger) PreDispatch(c *yarf.Context) error {
 	c.Response = &LoggerWriter{
 		Writer: c.Response,
+		Start:  time.Now(),
 	}
 
 	return nil
 }
 
+// End logs the request.
 func (l *Logger) End(c *yarf.Context) error {
-	// If nobody sets the status code, it's a 200
-	var code int
-	if _, ok := c.Response.(*LoggerWriter); ok {
-		code = c.Response.(*LoggerWriter).StatusCode
+	e := l.entry(c)
+
+	if l.JSON {
+		l.write(e.JSON(l.fields()))
+		return nil
 	}
 
-	if code == 0 {
-		code = 200
+	if l.Format != nil {
+		l.write(append(l.Format.Append(nil, c, e), '\n'))
+		return nil
 	}
 
 	log.Printf(
 		"| %s | %s | %d | %s",
-		c.GetClientIP(),
-		c.Request.Method,
-		code,
+		e.IP,
+		e.Method,
+		e.Status,
 		c.Request.URL.String(),
 	)
 
 	return nil
 }
+
+// fields returns the JSON fields to log.
+func (l *Logger) fields() []string {
+	if len(l.Fields) == 0 {
+		return DefaultFields
+	}
+
+	return l.Fields
+}
+
+// write writes a line to the Output.
+// Write errors are ignored, as the response is already sent.
+func (l *Logger) write(line []byte) {
+	out := l.Output
+	if out == nil {
+		out = log.Writer()
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	out.Write(line)
+}
@@ -0,0 +1,252 @@
+package ratelimit
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Backend interface is used to store the counters of a RateLimit out of the process,
+// so many instances of an application can share the same limits.
+type Backend interface {
+	// Incr atomically increments the counter of a key by n and returns the new value.
+	// If the key doesn't exist, it's created with the given expiration.
+	Incr(key string, n int, expiration time.Duration) (int64, error)
+
+	// Get returns the counter value of a key, or 0 if the key doesn't exist.
+	Get(key string) (int64, error)
+}
+
+// FailurePolicy sets how a RateLimit behaves when its Backend fails.
+type FailurePolicy int
+
+const (
+	// FailOpen allows the events while the Backend fails.
+	FailOpen FailurePolicy = iota
+
+	// FailClosed blocks the events while the Backend fails.
+	FailClosed
+)
+
+// BackendRate implements the fixed window algorithm for a single key using a Backend to store the counters.
+// The counters are created by RateLimit when a Backend is set.
+type BackendRate struct {
+	// Counters storage
+	Backend Backend
+
+	// Key on the Backend, without window suffix.
+	Key string
+
+	// Amount of events allowed in a given window
+	Limit int
+
+	// Time window to limit
+	Window time.Duration
+
+	// What to do when the Backend fails
+	OnFailure FailurePolicy
+
+	// Last count received from the Backend
+	EventCount int64
+
+	// Start time of the window for EventCount
+	Start time.Time
+
+	// Time source. Defaults to SystemClock.
+	// Instances sharing a Backend should have their clocks in sync.
+	Clock Clock
+
+	// Sync Mutex
+	sync.RWMutex
+}
+
+// window returns the start time of the window for a given time, and its Backend key.
+// Windows are aligned to the Unix epoch so all instances use the same keys.
+func (r *BackendRate) window(t time.Time) (time.Time, string) {
+	w := int64(r.Window)
+	if w <= 0 {
+		w = int64(time.Second)
+	}
+
+	idx := t.UnixNano() / w
+
+	return time.Unix(0, idx*w), r.Key + ":" + strconv.FormatInt(idx, 10)
+}
+
+// Count increments the counter for the actual window on the Backend.
+// If the limit has been reached it returns a RateLimitError error.
+// If the Backend fails, it blocks or allows the event depending on the FailurePolicy.
+func (r *BackendRate) Count() error {
+	return r.CountN(1)
+}
+
+// CountN increments the counter for the actual window by n, like Count.
+// Blocked events are counted on the Backend anyway, as counters can't be decremented atomically on every Backend.
+func (r *BackendRate) CountN(n int) error {
+	if n > r.Limit {
+		return CostError{}
+	}
+
+	start, key := r.window(now(r.Clock))
+
+	count, err := r.Backend.Incr(key, n, r.Window*2+time.Second)
+	if err != nil {
+		// Log error to default output
+		log.Println("ERROR: " + err.Error())
+
+		if r.OnFailure == FailClosed {
+			return RateLimitError{}
+		}
+
+		return nil
+	}
+
+	r.Lock()
+	r.EventCount = count
+	r.Start = start
+	r.Unlock()
+
+	// Block
+	if count > int64(r.Limit) {
+		return RateLimitError{}
+	}
+
+	// Continue
+	return nil
+}
+
+// Status returns the limit, the events still allowed and the window end, using the last count received from the Backend.
+func (r *BackendRate) Status() (limit, remaining int, reset time.Time) {
+	r.RLock()
+	defer r.RUnlock()
+
+	start, _ := r.window(now(r.Clock))
+	reset = start.Add(r.Window)
+
+	// Last count belongs to a previous window
+	if !r.Start.Equal(start) {
+		return r.Limit, r.Limit, reset
+	}
+
+	remaining = r.Limit - int(r.EventCount)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return r.Limit, remaining, reset
+}
+
+// Delay returns how long until the window ends if the last count reached the limit, 0 otherwise.
+func (r *BackendRate) Delay() time.Duration {
+	return r.DelayN(1)
+}
+
+// DelayN returns how long until the window ends if n events don't fit in it after the last count, 0 otherwise.
+func (r *BackendRate) DelayN(n int) time.Duration {
+	r.RLock()
+	defer r.RUnlock()
+
+	t := now(r.Clock)
+	start, _ := r.window(t)
+	if !r.Start.Equal(start) || r.EventCount+int64(n) <= int64(r.Limit) {
+		return 0
+	}
+
+	return start.Add(r.Window).Sub(t)
+}
+
+// Expired reports if the window of the last count ended at a given time.
+// The counters on the Backend expire by themselves.
+func (r *BackendRate) Expired(t time.Time) bool {
+	r.RLock()
+	defer r.RUnlock()
+
+	return !t.Before(r.Start.Add(r.Window))
+}
+
+// memoryCounter is the storage unit of MemoryBackend.
+type memoryCounter struct {
+	value      int64
+	expiration time.Time
+}
+
+// MemoryBackend is an in-process Backend implementation, mostly useful for testing.
+// Expired counters are removed while counting, so it doesn't need a garbage collector.
+type MemoryBackend struct {
+	// Counters
+	counters map[string]memoryCounter
+
+	// Next time to remove expired counters
+	nextSweep time.Time
+
+	// Time source. Defaults to SystemClock.
+	Clock Clock
+
+	// Sync Mutex
+	sync.Mutex
+}
+
+// NewMemoryBackend creates an empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		counters: make(map[string]memoryCounter),
+	}
+}
+
+// Incr increments the counter of a key by n.
+func (mb *MemoryBackend) Incr(key string, n int, expiration time.Duration) (int64, error) {
+	mb.Lock()
+	defer mb.Unlock()
+
+	t := now(mb.Clock)
+	if mb.counters == nil {
+		mb.counters = make(map[string]memoryCounter)
+	}
+
+	// Remove expired counters once a minute
+	if t.After(mb.nextSweep) {
+		mb.sweep(t)
+		mb.nextSweep = t.Add(time.Minute)
+	}
+
+	c, ok := mb.counters[key]
+	if !ok || !t.Before(c.expiration) {
+		c = memoryCounter{expiration: t.Add(expiration)}
+	}
+
+	c.value += int64(n)
+	mb.counters[key] = c
+
+	return c.value, nil
+}
+
+// Get returns the counter value of a key.
+func (mb *MemoryBackend) Get(key string) (int64, error) {
+	mb.Lock()
+	defer mb.Unlock()
+
+	if c, ok := mb.counters[key]; ok && now(mb.Clock).Before(c.expiration) {
+		return c.value, nil
+	}
+
+	return 0, nil
+}
+
+// Sweep removes all expired counters.
+func (mb *MemoryBackend) Sweep() {
+	mb.Lock()
+	defer mb.Unlock()
+
+	mb.sweep(now(mb.Clock))
+}
+
+// sweep removes the counters expired at a given time.
+// It must be called holding the lock.
+func (mb *MemoryBackend) sweep(now time.Time) {
+	for key, c := range mb.counters {
+		if !now.Before(c.expiration) {
+			delete(mb.counters, key)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingBackend struct{}
+
+func (b failingBackend) Incr(key string, n int, expiration time.Duration) (int64, error) {
+	return 0, errors.New("backend down")
+}
+
+func (b failingBackend) Get(key string) (int64, error) {
+	return 0, errors.New("backend down")
+}
+
+func TestMemoryBackend(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	mb.Incr("key", 1, time.Minute)
+	if v, _ := mb.Incr("key", 2, time.Minute); v != 3 {
+		t.Errorf("Expected 3, got %d", v)
+	}
+
+	mb.Incr("expired", 1, 0)
+	if v, _ := mb.Get("expired"); v != 0 {
+		t.Error("Expired counter still available")
+	}
+
+	mb.Sweep()
+	if _, ok := mb.counters["expired"]; ok {
+		t.Error("Expired counter not removed by Sweep")
+	}
+}
+
+func TestBackendRateLimit(t *testing.T) {
+	rl := NewWithBackend(context.Background(), NewMemoryBackend(), 2, time.Minute)
+	defer rl.Close()
+
+	rl.Count("key")
+	if _, remaining, _ := rl.Get("key").Status(); remaining != 1 {
+		t.Errorf("Expected 1 remaining, got %d", remaining)
+	}
+
+	rl.Count("key")
+	if err := rl.Count("key"); err == nil {
+		t.Error("Limit exceeded")
+	}
+}
+
+func TestBackendFailurePolicy(t *testing.T) {
+	rl := NewWithBackend(context.Background(), failingBackend{}, 2, time.Minute)
+	defer rl.Close()
+
+	if err := rl.Count("key"); err != nil {
+		t.Error("FailOpen blocked the event")
+	}
+
+	rl = NewWithBackend(context.Background(), failingBackend{}, 2, time.Minute)
+	rl.OnFailure = FailClosed
+	defer rl.Close()
+
+	if err := rl.Count("key"); err == nil {
+		t.Error("FailClosed allowed the event")
+	}
+}
@@ -0,0 +1,217 @@
+package backends
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/yarf-framework/extras/ratelimit"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listen starts a local stand-in server handling each connection with the given function.
+func listen(t *testing.T, handle func(r *bufio.Reader, w *bufio.Writer)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+				handle(bufio.NewReader(conn), bufio.NewWriter(conn))
+			}(conn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+// memcacheServer is a stand-in memcache server supporting the commands used by the backend.
+func memcacheServer(t *testing.T) string {
+	var mu sync.Mutex
+	data := make(map[string]string)
+
+	return listen(t, func(r *bufio.Reader, w *bufio.Writer) {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			f := strings.Fields(line)
+			if len(f) == 0 {
+				continue
+			}
+
+			mu.Lock()
+			switch f[0] {
+			case "incr":
+				v, ok := data[f[1]]
+				if !ok {
+					fmt.Fprint(w, "NOT_FOUND\r\n")
+					break
+				}
+				n, _ := strconv.Atoi(v)
+				d, _ := strconv.Atoi(f[2])
+				data[f[1]] = strconv.Itoa(n + d)
+				fmt.Fprintf(w, "%s\r\n", data[f[1]])
+
+			case "add":
+				size, _ := strconv.Atoi(f[4])
+				buf := make([]byte, size+2)
+				r.Read(buf)
+				if _, ok := data[f[1]]; ok {
+					fmt.Fprint(w, "NOT_STORED\r\n")
+					break
+				}
+				data[f[1]] = string(buf[:size])
+				fmt.Fprint(w, "STORED\r\n")
+
+			case "gets":
+				for _, k := range f[1:] {
+					if v, ok := data[k]; ok {
+						fmt.Fprintf(w, "VALUE %s 0 %d 1\r\n%s\r\n", k, len(v), v)
+					}
+				}
+				fmt.Fprint(w, "END\r\n")
+
+			default:
+				fmt.Fprint(w, "ERROR\r\n")
+			}
+			mu.Unlock()
+
+			w.Flush()
+		}
+	})
+}
+
+// redisServer is a stand-in redis server supporting the commands used by the backend.
+func redisServer(t *testing.T) string {
+	var mu sync.Mutex
+	data := make(map[string]int64)
+
+	// exec runs a command and returns its RESP reply.
+	exec := func(cmd []string) string {
+		switch strings.ToUpper(cmd[0]) {
+		case "SET":
+			if _, ok := data[cmd[1]]; ok {
+				return "$-1\r\n"
+			}
+			n, _ := strconv.ParseInt(cmd[2], 10, 64)
+			data[cmd[1]] = n
+			return "+OK\r\n"
+
+		case "INCRBY":
+			n, _ := strconv.ParseInt(cmd[2], 10, 64)
+			data[cmd[1]] += n
+			return fmt.Sprintf(":%d\r\n", data[cmd[1]])
+
+		case "GET":
+			v, ok := data[cmd[1]]
+			if !ok {
+				return "$-1\r\n"
+			}
+			s := strconv.FormatInt(v, 10)
+			return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+		}
+
+		return "-ERR unknown command\r\n"
+	}
+
+	return listen(t, func(r *bufio.Reader, w *bufio.Writer) {
+		var queue [][]string
+		multi := false
+
+		for {
+			// Array of bulk strings
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			cmd := make([]string, n)
+			for i := range cmd {
+				r.ReadString('\n')
+				arg, _ := r.ReadString('\n')
+				cmd[i] = strings.TrimRight(arg, "\r\n")
+			}
+
+			mu.Lock()
+			switch {
+			case strings.ToUpper(cmd[0]) == "MULTI":
+				multi = true
+				fmt.Fprint(w, "+OK\r\n")
+
+			case strings.ToUpper(cmd[0]) == "EXEC":
+				fmt.Fprintf(w, "*%d\r\n", len(queue))
+				for _, c := range queue {
+					fmt.Fprint(w, exec(c))
+				}
+				queue, multi = nil, false
+
+			case multi:
+				queue = append(queue, cmd)
+				fmt.Fprint(w, "+QUEUED\r\n")
+
+			default:
+				fmt.Fprint(w, exec(cmd))
+			}
+			mu.Unlock()
+
+			w.Flush()
+		}
+	})
+}
+
+func testBackend(t *testing.T, b ratelimit.Backend) {
+	for i := int64(1); i <= 3; i++ {
+		v, err := b.Incr("some key", 1, time.Minute)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if v != i {
+			t.Errorf("Expected %d, got %d", i, v)
+		}
+	}
+
+	if v, _ := b.Incr("some key", 5, time.Minute); v != 8 {
+		t.Errorf("Expected 8 after incrementing by 5, got %d", v)
+	}
+
+	if v, err := b.Get("some key"); err != nil || v != 8 {
+		t.Errorf("Expected 8 from Get, got %d", v)
+	}
+	if v, err := b.Get("missing"); err != nil || v != 0 {
+		t.Errorf("Expected 0 for missing key, got %d", v)
+	}
+
+	// Shared limit
+	rl := ratelimit.NewWithBackend(context.Background(), b, 2, time.Minute)
+	defer rl.Close()
+	other := ratelimit.NewWithBackend(context.Background(), b, 2, time.Minute)
+	defer other.Close()
+
+	rl.Count("client")
+	other.Count("client")
+	if err := rl.Count("client"); err == nil {
+		t.Error("Limit not shared between instances")
+	}
+}
+
+func TestMemcache(t *testing.T) {
+	testBackend(t, Memcache(memcacheServer(t)))
+}
+
+func TestRedis(t *testing.T) {
+	testBackend(t, Redis(redisServer(t)))
+}
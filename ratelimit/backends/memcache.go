@@ -0,0 +1,86 @@
+package backends
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/yarf-framework/extras/ratelimit"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	keyPrefix = "github.com/yarf-framework/extras/ratelimit/backends:"
+)
+
+// key returns a valid memcache or redis key for any rate limit key.
+func key(k string) string {
+	return fmt.Sprintf("%s%x", keyPrefix, sha1.Sum([]byte(k)))
+}
+
+// seconds converts an expiration to seconds, rounding up to at least 1 second.
+func seconds(d time.Duration) int32 {
+	s := int32((d + time.Second - 1) / time.Second)
+	if s < 1 {
+		s = 1
+	}
+
+	return s
+}
+
+type memcacheBackend struct {
+	client *memcache.Client
+}
+
+// Memcache creates a ratelimit.Backend that stores the counters on the given memcache servers.
+// Counters are incremented with "incr", and created with "add" to keep it atomic across clients.
+func Memcache(servers ...string) ratelimit.Backend {
+	mb := new(memcacheBackend)
+	mb.client = memcache.New(servers...)
+
+	return mb
+}
+
+// Incr increments the counter of a key by n.
+func (mb *memcacheBackend) Incr(k string, n int, expiration time.Duration) (int64, error) {
+	for {
+		v, err := mb.client.Increment(key(k), uint64(n))
+		if err == nil {
+			return int64(v), nil
+		}
+		if err != memcache.ErrCacheMiss {
+			return 0, err
+		}
+
+		// Create
+		err = mb.client.Add(&memcache.Item{
+			Key:        key(k),
+			Value:      []byte(strconv.Itoa(n)),
+			Expiration: seconds(expiration),
+		})
+		if err == nil {
+			return int64(n), nil
+		}
+
+		// Created by someone else in the meantime: increment again.
+		if err != memcache.ErrNotStored {
+			return 0, err
+		}
+	}
+}
+
+// Get returns the counter value of a key.
+func (mb *memcacheBackend) Get(k string) (int64, error) {
+	item, err := mb.client.Get(key(k))
+	if err != nil {
+		if err == memcache.ErrCacheMiss {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	// memcache pads decremented values with spaces
+	return strconv.ParseInt(strings.TrimSpace(string(item.Value)), 10, 64)
+}
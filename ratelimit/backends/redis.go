@@ -0,0 +1,67 @@
+package backends
+
+import (
+	"github.com/gomodule/redigo/redis"
+	"github.com/yarf-framework/extras/ratelimit"
+	"time"
+)
+
+type redisBackend struct {
+	pool *redis.Pool
+}
+
+// RedisTimeout is the default connect, read and write timeout of the Redis backends.
+// A slow server delays every counted request, so it's kept short.
+var RedisTimeout = 100 * time.Millisecond
+
+// Redis creates a ratelimit.Backend that stores the counters on the given redis server address.
+// Counters are created with their expiration and incremented inside a MULTI/EXEC transaction.
+// Connections use RedisTimeout, which can be overridden by the options, like redis.DialReadTimeout(time.Second).
+func Redis(address string, options ...redis.DialOption) ratelimit.Backend {
+	options = append([]redis.DialOption{
+		redis.DialConnectTimeout(RedisTimeout),
+		redis.DialReadTimeout(RedisTimeout),
+		redis.DialWriteTimeout(RedisTimeout),
+	}, options...)
+
+	rb := new(redisBackend)
+	rb.pool = &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 5 * time.Minute,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", address, options...)
+		},
+	}
+
+	return rb
+}
+
+// Incr increments the counter of a key by n.
+func (rb *redisBackend) Incr(k string, n int, expiration time.Duration) (int64, error) {
+	conn := rb.pool.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("SET", key(k), 0, "PX", int64(expiration/time.Millisecond), "NX")
+	conn.Send("INCRBY", key(k), n)
+
+	values, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return 0, err
+	}
+
+	return redis.Int64(values[1], nil)
+}
+
+// Get returns the counter value of a key.
+func (rb *redisBackend) Get(k string) (int64, error) {
+	conn := rb.pool.Get()
+	defer conn.Close()
+
+	v, err := redis.Int64(conn.Do("GET", key(k)))
+	if err == redis.ErrNil {
+		return 0, nil
+	}
+
+	return v, err
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Compare with the unsharded store, across GOMAXPROCS values:
+//
+//	go test -run NONE -bench Count -cpu 1,2,4,8 ./ratelimit
+
+// mutexRateLimit is the counter store used before sharding, with a single lock for all keys, as a baseline.
+// Like the original RateLimit.Count, it holds the write lock while counting.
+type mutexRateLimit struct {
+	counter map[string]Limiter
+	sync.RWMutex
+}
+
+func (rl *mutexRateLimit) Count(key string) error {
+	rl.Lock()
+	defer rl.Unlock()
+
+	if _, ok := rl.counter[key]; !ok {
+		rl.counter[key] = FixedWindow(1<<30, time.Minute)(nil)
+	}
+
+	return rl.counter[key].Count()
+}
+
+// benchKeys returns n client IPs.
+func benchKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "10." + strconv.Itoa(i>>16&255) + "." + strconv.Itoa(i>>8&255) + "." + strconv.Itoa(i&255)
+	}
+
+	return keys
+}
+
+func benchmarkCount(b *testing.B, count func(string) error) {
+	keys := benchKeys(4096)
+	var seq uint32
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		// Each goroutine starts at a different key
+		i := int(atomic.AddUint32(&seq, 997))
+		for pb.Next() {
+			count(keys[i&(len(keys)-1)])
+			i++
+		}
+	})
+}
+
+func BenchmarkCountSharded(b *testing.B) {
+	rl := NewWithWindow(context.Background(), 1<<30, time.Minute)
+	defer rl.Close()
+
+	benchmarkCount(b, rl.Count)
+}
+
+func BenchmarkCountMutex(b *testing.B) {
+	rl := &mutexRateLimit{counter: make(map[string]Limiter)}
+
+	benchmarkCount(b, rl.Count)
+}
+
+func BenchmarkCountShardedSweep(b *testing.B) {
+	rl := NewWithWindow(context.Background(), 1<<30, time.Minute)
+	rl.SweepInterval = time.Millisecond
+	defer rl.Close()
+
+	benchmarkCount(b, rl.Count)
+}
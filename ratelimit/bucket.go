@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Bucket implements the token bucket algorithm for a single key.
+// Each event takes a token from the bucket, that gets refilled at a constant rate up to its burst size.
+// Unlike Rate, it doesn't allow twice the limit around a window boundary.
+type Bucket struct {
+	// Tokens added per second
+	Rate float64
+
+	// Bucket size: max amount of events allowed at once
+	Burst int
+
+	// Available tokens at Last
+	Tokens float64
+
+	// Last refill time
+	Last time.Time
+
+	// Time source. Defaults to SystemClock.
+	Clock Clock
+
+	// Sync Mutex
+	sync.RWMutex
+}
+
+// NewBucket creates a full Bucket.
+func NewBucket(rate float64, burst int) *Bucket {
+	return &Bucket{
+		Rate:   rate,
+		Burst:  burst,
+		Tokens: float64(burst),
+		Last:   time.Now(),
+	}
+}
+
+// refill adds the tokens generated since the last refill.
+// It must be called holding the write lock.
+func (b *Bucket) refill(now time.Time) {
+	if now.After(b.Last) {
+		b.Tokens = math.Min(float64(b.Burst), b.Tokens+now.Sub(b.Last).Seconds()*b.Rate)
+		b.Last = now
+	}
+}
+
+// Count takes a token from the bucket.
+// If the bucket is empty it returns a RateLimitError error.
+func (b *Bucket) Count() error {
+	return b.CountN(1)
+}
+
+// CountN takes n tokens from the bucket.
+// If there aren't enough tokens it returns a RateLimitError error and none is taken.
+func (b *Bucket) CountN(n int) error {
+	if n > b.Burst {
+		return CostError{}
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.refill(now(b.Clock))
+
+	// Block
+	if b.Tokens < float64(n) {
+		return RateLimitError{}
+	}
+
+	b.Tokens -= float64(n)
+
+	// Continue
+	return nil
+}
+
+// Status returns the bucket size, the available tokens and the time when the bucket will be full again.
+func (b *Bucket) Status() (limit, remaining int, reset time.Time) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.refill(now(b.Clock))
+
+	return b.Burst, int(b.Tokens), b.full()
+}
+
+// Expired reports if the bucket is full at a given time, so it's the same as a new one.
+func (b *Bucket) Expired(t time.Time) bool {
+	b.RLock()
+	defer b.RUnlock()
+
+	return !t.Before(b.full())
+}
+
+// full returns the time when the bucket will be full.
+func (b *Bucket) full() time.Time {
+	if b.Rate <= 0 {
+		return b.Last
+	}
+
+	missing := float64(b.Burst) - b.Tokens
+
+	return b.Last.Add(time.Duration(missing / b.Rate * float64(time.Second)))
+}
+
+// Delay returns how long until a token is available, 0 if there is one now.
+func (b *Bucket) Delay() time.Duration {
+	return b.DelayN(1)
+}
+
+// DelayN returns how long until n tokens are available, 0 if they are now.
+func (b *Bucket) DelayN(n int) time.Duration {
+	b.Lock()
+	defer b.Unlock()
+
+	b.refill(now(b.Clock))
+	if b.Tokens >= float64(n) {
+		return 0
+	}
+	if b.Rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration((float64(n) - b.Tokens) / b.Rate * float64(time.Second))
+}
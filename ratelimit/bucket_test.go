@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	b := NewBucket(1, 3)
+
+	for i := 0; i < 3; i++ {
+		if err := b.Count(); err != nil {
+			t.Fatalf("Event %d blocked: %s", i, err.Error())
+		}
+	}
+
+	if err := b.Count(); err == nil {
+		t.Error("Empty bucket allowed an event")
+	}
+
+	limit, remaining, reset := b.Status()
+	if limit != 3 || remaining != 0 {
+		t.Errorf("Expected 3/0, got %d/%d", limit, remaining)
+	}
+	if reset.Sub(time.Now()) > 3*time.Second || reset.Before(time.Now()) {
+		t.Error("Wrong reset time")
+	}
+
+	// Refill one token
+	b.Last = b.Last.Add(-time.Second)
+	if err := b.Count(); err != nil {
+		t.Error("Refilled token not available")
+	}
+}
+
+func TestBucketExpired(t *testing.T) {
+	b := NewBucket(1, 2)
+	if !b.Expired(time.Now()) {
+		t.Error("Full bucket not expired")
+	}
+
+	b.Count()
+	if b.Expired(time.Now()) {
+		t.Error("Used bucket expired")
+	}
+	if !b.Expired(time.Now().Add(time.Second)) {
+		t.Error("Refilled bucket not expired")
+	}
+}
+
+func TestTokenBucketAlgorithm(t *testing.T) {
+	rl := NewWithAlgorithm(context.Background(), TokenBucket(10, 2))
+	defer rl.Close()
+
+	rl.Count("key")
+	rl.Count("key")
+	if err := rl.Count("key"); err == nil {
+		t.Error("Burst exceeded")
+	}
+
+	if _, ok := rl.Get("key").(*Bucket); !ok {
+		t.Error("Algorithm not used")
+	}
+}
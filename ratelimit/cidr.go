@@ -0,0 +1,211 @@
+package ratelimit
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// cidrNode is a binary prefix trie node, one level per address bit.
+type cidrNode struct {
+	children [2]*cidrNode
+
+	// A range ends here
+	end bool
+}
+
+// CIDRList is a set of IP ranges stored on a prefix trie, so lookups cost at most one step per address bit
+// no matter how many ranges it contains. It's safe for concurrent use and can be reloaded at any time.
+type CIDRList struct {
+	// IPv4 and IPv6 tries
+	v4, v6 *cidrNode
+
+	// Amount of ranges
+	count int
+
+	// Sync Mutex
+	sync.RWMutex
+}
+
+// NewCIDRList creates a list from ranges in CIDR notation, like "10.0.0.0/8" or "2001:db8::/32".
+// Single IP addresses are accepted as well.
+func NewCIDRList(cidrs ...string) (*CIDRList, error) {
+	l := new(CIDRList)
+
+	for _, cidr := range cidrs {
+		if err := l.Add(cidr); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// parseCIDR parses a range in CIDR notation or a single IP address.
+// IPv4-mapped IPv6 ranges, like "::ffff:10.0.0.0/104", are converted to IPv4 ranges.
+func parseCIDR(cidr string) (*net.IPNet, error) {
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "CIDR address", Text: cidr}
+		}
+		if ip.To4() != nil {
+			return &net.IPNet{IP: ip.To4(), Mask: net.CIDRMask(32, 32)}, nil
+		}
+
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	// IPv4-mapped range. Shorter masks clear the "::ffff:" prefix, so they're IPv6 ranges.
+	ones, bits := n.Mask.Size()
+	if ip4 := n.IP.To4(); ip4 != nil && bits == 128 {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(ones-96, 32)}, nil
+	}
+
+	return n, nil
+}
+
+// Add inserts a range in CIDR notation or a single IP address.
+func (l *CIDRList) Add(cidr string) error {
+	n, err := parseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.insert(n)
+
+	return nil
+}
+
+// insert adds a parsed range to the tries.
+// It must be called holding the write lock.
+func (l *CIDRList) insert(n *net.IPNet) {
+	root := &l.v6
+	ip := n.IP.To16()
+	if ip4 := n.IP.To4(); ip4 != nil {
+		root = &l.v4
+		ip = ip4
+	}
+
+	if *root == nil {
+		*root = new(cidrNode)
+	}
+
+	ones, _ := n.Mask.Size()
+	node := *root
+	for i := 0; i < ones; i++ {
+		// Shorter range already covers this one
+		if node.end {
+			return
+		}
+
+		b := ip[i/8] >> uint(7-i%8) & 1
+		if node.children[b] == nil {
+			node.children[b] = new(cidrNode)
+		}
+		node = node.children[b]
+	}
+
+	if !node.end {
+		node.end = true
+		l.count++
+	}
+}
+
+// Contains reports if the IP address is inside any range of the list.
+func (l *CIDRList) Contains(ip net.IP) bool {
+	l.RLock()
+	defer l.RUnlock()
+
+	node := l.v6
+	if ip4 := ip.To4(); ip4 != nil {
+		node = l.v4
+		ip = ip4
+	} else if ip = ip.To16(); ip == nil {
+		return false
+	}
+
+	for i := 0; node != nil; i++ {
+		if node.end {
+			return true
+		}
+		if i == len(ip)*8 {
+			return false
+		}
+
+		node = node.children[ip[i/8]>>uint(7-i%8)&1]
+	}
+
+	return false
+}
+
+// ContainsString parses the IP address and reports if it's inside any range of the list.
+func (l *CIDRList) ContainsString(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	return l.Contains(parsed)
+}
+
+// Len returns the amount of ranges in the list.
+func (l *CIDRList) Len() int {
+	l.RLock()
+	defer l.RUnlock()
+
+	return l.count
+}
+
+// Load replaces the list with the ranges read from r, one per line.
+// Empty lines and lines starting with "#" are ignored.
+// If any line isn't valid, it returns the error and the list isn't changed.
+func (l *CIDRList) Load(r io.Reader) error {
+	tmp := new(CIDRList)
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		n, err := parseCIDR(line)
+		if err != nil {
+			return err
+		}
+		tmp.insert(n)
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	l.Lock()
+	l.v4, l.v6, l.count = tmp.v4, tmp.v6, tmp.count
+	l.Unlock()
+
+	return nil
+}
+
+// LoadFile replaces the list with the ranges read from a file. See Load.
+// It can be called at any time to reload the file, like on SIGHUP.
+func (l *CIDRList) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return l.Load(f)
+}
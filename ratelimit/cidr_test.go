@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestCIDRList(t *testing.T) {
+	l, err := NewCIDRList("10.0.0.0/8", "192.168.1.10", "2001:db8::/32")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := map[string]bool{
+		"10.1.2.3":        true,
+		"11.0.0.1":        false,
+		"192.168.1.10":    true,
+		"192.168.1.11":    false,
+		"2001:db8:1::1":   true,
+		"2001:db9::1":     false,
+		"::ffff:10.0.0.1": true,
+		"invalid":         false,
+	}
+
+	for ip, expected := range tests {
+		if l.ContainsString(ip) != expected {
+			t.Errorf("%s: expected %v", ip, expected)
+		}
+	}
+
+	if _, err := NewCIDRList("10.0.0.0/33"); err == nil {
+		t.Error("Invalid range accepted")
+	}
+}
+
+func TestCIDRListMapped(t *testing.T) {
+	l, err := NewCIDRList("::ffff:10.0.0.0/104")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !l.ContainsString("10.1.2.3") || l.ContainsString("11.0.0.1") {
+		t.Error("Mapped range not matched as IPv4")
+	}
+
+	// Reloaded from a list
+	if err := l.Load(strings.NewReader("::ffff:192.168.0.0/112\n")); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !l.ContainsString("192.168.1.1") || l.ContainsString("10.1.2.3") {
+		t.Error("Loaded mapped range not matched as IPv4")
+	}
+}
+
+func TestCIDRListLoad(t *testing.T) {
+	l, _ := NewCIDRList("10.0.0.0/8")
+
+	err := l.Load(strings.NewReader("# Abusive ranges\n\n203.0.113.0/24\n198.51.100.7\n"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if l.ContainsString("10.0.0.1") {
+		t.Error("Load didn't replace the list")
+	}
+	if !l.ContainsString("203.0.113.50") || !l.ContainsString("198.51.100.7") {
+		t.Error("Loaded ranges not found")
+	}
+	if l.Len() != 2 {
+		t.Errorf("Expected 2 ranges, got %d", l.Len())
+	}
+
+	// Invalid file doesn't change the list
+	if err := l.Load(strings.NewReader("invalid\n")); err == nil {
+		t.Error("Invalid line accepted")
+	}
+	if l.Len() != 2 {
+		t.Error("List changed by an invalid load")
+	}
+}
+
+func TestRateLimiterCIDR(t *testing.T) {
+	m := YarfMiddleware(1, 60)
+	defer m.Close()
+	m.Allow, _ = NewCIDRList("10.0.0.0/8")
+	m.Deny, _ = NewCIDRList("203.0.113.0/24")
+
+	for i := 0; i < 3; i++ {
+		if err := m.PreDispatch(newTestContext("GET", "/", "10.0.0.1")); err != nil {
+			t.Error("Allowed range limited")
+		}
+	}
+
+	if _, ok := m.PreDispatch(newTestContext("GET", "/", "203.0.113.1")).(*DeniedError); !ok {
+		t.Error("Denied range not blocked")
+	}
+}
+
+func TestRateLimiterCIDRSpoofed(t *testing.T) {
+	m := YarfMiddleware(1, 60)
+	defer m.Close()
+	m.Allow, _ = NewCIDRList("10.0.0.0/8")
+	m.Deny, _ = NewCIDRList("203.0.113.0/24")
+
+	// A client sending its own X-Forwarded-For can't join the allow list.
+	var err error
+	for i := 0; i < 2; i++ {
+		c := newTestContext("GET", "/", "198.51.100.7")
+		c.Request.Header.Set("X-Forwarded-For", "10.1.1.1")
+		err = m.PreDispatch(c)
+	}
+	if err == nil {
+		t.Error("Spoofed header matched the allow list")
+	}
+
+	// Nor escape the deny list.
+	c := newTestContext("GET", "/", "203.0.113.1")
+	c.Request.Header.Set("X-Forwarded-For", "10.1.1.1")
+	if _, ok := m.PreDispatch(c).(*DeniedError); !ok {
+		t.Error("Spoofed header escaped the deny list")
+	}
+}
+
+func BenchmarkCIDRList(b *testing.B) {
+	l := new(CIDRList)
+	for i := 0; i < 10000; i++ {
+		l.Add(fmt.Sprintf("%d.%d.%d.0/24", 1+i/65536, i/256%256, i%256))
+	}
+	ip := net.ParseIP("1.20.30.40")
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		l.Contains(ip)
+	}
+}
@@ -0,0 +1,213 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the time to the rate limiters and their garbage collectors, so it can be replaced on tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTicker returns a Ticker that ticks every d.
+	NewTicker(d time.Duration) Ticker
+
+	// After returns a channel that receives the time after d.
+	After(d time.Duration) <-chan time.Time
+}
+
+// Ticker delivers ticks on a channel at intervals.
+type Ticker interface {
+	// Chan returns the channel where the ticks are delivered.
+	Chan() <-chan time.Time
+
+	// Stop turns off the ticker.
+	Stop()
+}
+
+// systemClock implements Clock using the time package.
+type systemClock struct{}
+
+// Now returns time.Now().
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTicker wraps a time.Ticker.
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+// After wraps time.After.
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// systemTicker implements Ticker using a time.Ticker.
+type systemTicker struct {
+	*time.Ticker
+}
+
+// Chan returns the time.Ticker channel.
+func (t systemTicker) Chan() <-chan time.Time {
+	return t.C
+}
+
+// SystemClock is the Clock used when none is set.
+var SystemClock Clock = systemClock{}
+
+// now returns the current time of a Clock, using SystemClock when nil.
+func now(c Clock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+
+	return c.Now()
+}
+
+// clockOrSystem returns the Clock, or SystemClock when nil.
+func clockOrSystem(c Clock) Clock {
+	if c == nil {
+		return SystemClock
+	}
+
+	return c
+}
+
+// FakeClock is a Clock that only moves when told to, for deterministic tests.
+type FakeClock struct {
+	// Current time
+	now time.Time
+
+	// Running tickers
+	tickers []*fakeTicker
+
+	// Pending After calls
+	timers []fakeTimer
+
+	// Sync Mutex
+	sync.Mutex
+}
+
+// NewFakeClock creates a FakeClock stopped at the given time.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{
+		now: t,
+	}
+}
+
+// Now returns the FakeClock time.
+func (c *FakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d, delivering a tick to each ticker whose interval has elapsed.
+// Like time.Ticker, ticks are dropped for slow receivers.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.now = c.now.Add(d)
+
+	for _, t := range c.tickers {
+		if t.next.After(c.now) {
+			continue
+		}
+
+		select {
+		case t.c <- c.now:
+		default:
+		}
+
+		for !t.next.After(c.now) {
+			t.next = t.next.Add(t.d)
+		}
+	}
+
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+
+		t.c <- c.now
+	}
+	c.timers = pending
+}
+
+// After returns a channel that receives the FakeClock time once it's advanced by d.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), c: ch})
+
+	return ch
+}
+
+// Waiters returns the amount of pending After calls, so tests can wait for a goroutine to block on the clock.
+func (c *FakeClock) Waiters() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.timers)
+}
+
+// NewTicker returns a Ticker that ticks every d of FakeClock time.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	c.Lock()
+	defer c.Unlock()
+
+	t := &fakeTicker{
+		c:     make(chan time.Time, 1),
+		d:     d,
+		next:  c.now.Add(d),
+		clock: c,
+	}
+	c.tickers = append(c.tickers, t)
+
+	return t
+}
+
+// fakeTimer is a pending FakeClock.After call.
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// fakeTicker implements Ticker for FakeClock.
+type fakeTicker struct {
+	c     chan time.Time
+	d     time.Duration
+	next  time.Time
+	clock *FakeClock
+}
+
+// Chan returns the ticks channel.
+func (t *fakeTicker) Chan() <-chan time.Time {
+	return t.c
+}
+
+// Stop removes the ticker from its clock.
+func (t *fakeTicker) Stop() {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+
+	for i, ticker := range t.clock.tickers {
+		if ticker == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFakeClockWindows(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	rl := NewWithWindow(context.Background(), 2, 250*time.Millisecond)
+	rl.Clock = clock
+	defer rl.Close()
+
+	rl.Count("key")
+	rl.Count("key")
+	if err := rl.Count("key"); err == nil {
+		t.Error("Limit exceeded")
+	}
+
+	_, remaining, reset := rl.Get("key").Status()
+	if remaining != 0 || !reset.Equal(time.Unix(1000, 250*int64(time.Millisecond))) {
+		t.Errorf("Unexpected status: %d remaining, reset at %v", remaining, reset)
+	}
+
+	// Sub-second window reset
+	clock.Advance(300 * time.Millisecond)
+	if err := rl.Count("key"); err != nil {
+		t.Error("Window didn't reset")
+	}
+}
+
+func TestFakeClockHeaders(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	m := YarfMiddleware(1, 60)
+	m.rl.Clock = clock
+	defer m.Close()
+
+	m.PreDispatch(newTestContext("GET", "/", "10.0.0.1"))
+
+	clock.Advance(20 * time.Second)
+	c := newTestContext("GET", "/", "10.0.0.1")
+	m.PreDispatch(c)
+
+	h := c.Response.Header()
+	if h.Get("X-RateLimit-Reset") != "1060" || h.Get("RateLimit-Reset") != "40" || h.Get("Retry-After") != "40" {
+		t.Errorf("Unexpected headers: %v", h)
+	}
+}
+
+func TestFakeClockSweep(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	rl := New(10, 1)
+	rl.Clock = clock
+	defer rl.Close()
+
+	rl.Count("key")
+
+	clock.Advance(3 * time.Second)
+	rl.Sweep()
+
+	if _, ok := rl.counter.lookup("key"); ok {
+		t.Error("Expired key not removed by Sweep")
+	}
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-ticker.Chan():
+		t.Error("Early tick")
+	default:
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case tick := <-ticker.Chan():
+		if !tick.Equal(time.Unix(1, 0)) {
+			t.Errorf("Unexpected tick time %v", tick)
+		}
+	default:
+		t.Error("Missing tick")
+	}
+}
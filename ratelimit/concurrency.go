@@ -0,0 +1,233 @@
+package ratelimit
+
+import (
+	"github.com/yarf-framework/yarf"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ConcurrencyError is returned by the ConcurrencyLimiter when no slot is available.
+type ConcurrencyError struct {
+	// HTTP status code
+	code int
+}
+
+// Implements the error interface returning the ErrorMsg value of each error.
+func (e *ConcurrencyError) Error() string {
+	return http.StatusText(e.Code())
+}
+
+// Code returns the error's HTTP code to be used in the response.
+func (e *ConcurrencyError) Code() int {
+	if e.code == 0 {
+		return 429
+	}
+
+	return e.code
+}
+
+// ID returns the error's ID for further reference.
+func (e *ConcurrencyError) ID() int {
+	return e.Code()
+}
+
+// Msg returns the error's message, used to implement the Error interface.
+func (e *ConcurrencyError) Msg() string {
+	return http.StatusText(e.Code())
+}
+
+// Body returns the error's content body, if needed, to be returned in the HTTP response.
+func (e *ConcurrencyError) Body() string {
+	return http.StatusText(e.Code()) + ": Too many requests in progress."
+}
+
+// ConcurrencyLimiter middleware caps the amount of requests in progress at the same time,
+// both globally and per key, to protect expensive endpoints from many slow requests at once.
+// Slots are taken on PreDispatch and released on PostDispatch or End, whichever comes first.
+type ConcurrencyLimiter struct {
+	yarf.Middleware
+
+	// Max requests in progress. 0 means no global limit.
+	Global int
+
+	// Max requests in progress per key. 0 means no per key limit.
+	PerKey int
+
+	// Extracts the key of each request. Defaults to ClientNet64.
+	// Requests with an empty key are only limited by the global cap.
+	Key KeyFunc
+
+	// Max requests waiting for a slot. 0 rejects the requests right away when there is no slot available.
+	Queue int
+
+	// Max time waiting in the queue. 0 waits until a slot is available or the request is cancelled.
+	QueueTimeout time.Duration
+
+	// HTTP status code of the rejected requests, like 503. Defaults to 429.
+	RejectCode int
+
+	// Requests in progress
+	inflight int
+
+	// Requests in progress per key
+	perKey map[string]int
+
+	// Requests in the queue
+	waiting int
+
+	// Notified when a slot is released
+	waiters []chan struct{}
+
+	// Keys of the requests holding a slot
+	slots map[*yarf.Context]string
+
+	// Sync Mutex
+	sync.Mutex
+}
+
+// NewConcurrencyLimiter creates the middleware allowing global requests in progress,
+// and perKey requests in progress per client IPv4 address or IPv6 /64 network.
+func NewConcurrencyLimiter(global, perKey int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		Global: global,
+		PerKey: perKey,
+	}
+}
+
+// PreDispatch takes a slot for the request, waiting in the queue if configured.
+func (m *ConcurrencyLimiter) PreDispatch(c *yarf.Context) error {
+	key := keyOrDefault(m.Key)(c)
+
+	m.Lock()
+
+	if m.perKey == nil {
+		m.perKey = make(map[string]int)
+		m.slots = make(map[*yarf.Context]string)
+	}
+
+	if m.acquire(c, key) {
+		m.Unlock()
+		return nil
+	}
+
+	// Queue
+	if m.waiting >= m.Queue {
+		m.Unlock()
+		return &ConcurrencyError{code: m.RejectCode}
+	}
+	m.waiting++
+
+	var timeout <-chan time.Time
+	if m.QueueTimeout > 0 {
+		t := time.NewTimer(m.QueueTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	for {
+		ch := make(chan struct{})
+		m.waiters = append(m.waiters, ch)
+		m.Unlock()
+
+		select {
+		case <-ch:
+
+		case <-timeout:
+			return m.leave(ch)
+
+		case <-c.Request.Context().Done():
+			return m.leave(ch)
+		}
+
+		m.Lock()
+		if m.acquire(c, key) {
+			m.waiting--
+			m.Unlock()
+			return nil
+		}
+	}
+}
+
+// acquire takes a slot if available.
+// It must be called holding the lock.
+func (m *ConcurrencyLimiter) acquire(c *yarf.Context, key string) bool {
+	if m.Global > 0 && m.inflight >= m.Global {
+		return false
+	}
+	if m.PerKey > 0 && key != "" && m.perKey[key] >= m.PerKey {
+		return false
+	}
+
+	m.inflight++
+	if key != "" {
+		m.perKey[key]++
+	}
+	m.slots[c] = key
+
+	return true
+}
+
+// leave removes a waiter from the queue and returns the rejection error.
+func (m *ConcurrencyLimiter) leave(ch chan struct{}) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.waiting--
+	for i, w := range m.waiters {
+		if w == ch {
+			m.waiters = append(m.waiters[:i], m.waiters[i+1:]...)
+			break
+		}
+	}
+
+	return &ConcurrencyError{code: m.RejectCode}
+}
+
+// release frees the slot held by the request, if any, and wakes up the queue.
+func (m *ConcurrencyLimiter) release(c *yarf.Context) {
+	m.Lock()
+	defer m.Unlock()
+
+	key, ok := m.slots[c]
+	if !ok {
+		return
+	}
+	delete(m.slots, c)
+
+	m.inflight--
+	if key != "" {
+		m.perKey[key]--
+		if m.perKey[key] <= 0 {
+			delete(m.perKey, key)
+		}
+	}
+
+	// Wake up all waiters to retry
+	for _, ch := range m.waiters {
+		close(ch)
+	}
+	m.waiters = nil
+}
+
+// PostDispatch releases the request slot.
+func (m *ConcurrencyLimiter) PostDispatch(c *yarf.Context) error {
+	m.release(c)
+
+	return nil
+}
+
+// End releases the request slot if PostDispatch didn't, like after an error.
+func (m *ConcurrencyLimiter) End(c *yarf.Context) error {
+	m.release(c)
+
+	return nil
+}
+
+// InFlight returns the amount of requests in progress, globally and for a key.
+func (m *ConcurrencyLimiter) InFlight(key string) (global, perKey int) {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.inflight, m.perKey[key]
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	m := NewConcurrencyLimiter(3, 2)
+
+	c1 := newTestContext("GET", "/report", "10.0.0.1")
+	c2 := newTestContext("GET", "/report", "10.0.0.1")
+	c3 := newTestContext("GET", "/report", "10.0.0.1")
+
+	if err := m.PreDispatch(c1); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := m.PreDispatch(c2); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Per key cap
+	if err := m.PreDispatch(c3); err == nil {
+		t.Error("Per key cap exceeded")
+	} else if err.(*ConcurrencyError).Code() != 429 {
+		t.Error("Unexpected status code")
+	}
+
+	// Global cap
+	if err := m.PreDispatch(newTestContext("GET", "/report", "10.0.0.2")); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := m.PreDispatch(newTestContext("GET", "/report", "10.0.0.3")); err == nil {
+		t.Error("Global cap exceeded")
+	}
+
+	// Release on error: End without PostDispatch
+	m.End(c1)
+	m.PostDispatch(c2)
+	m.End(c2)
+	if g, k := m.InFlight("10.0.0.1"); g != 1 || k != 0 {
+		t.Errorf("Expected 1/0 in flight, got %d/%d", g, k)
+	}
+
+	// Rejected requests don't release other slots
+	m.End(c3)
+	if g, _ := m.InFlight(""); g != 1 {
+		t.Error("Rejected request released a slot")
+	}
+}
+
+func TestConcurrencyLimiterNet64(t *testing.T) {
+	m := NewConcurrencyLimiter(0, 1)
+
+	// Rotating addresses within the /64
+	c := newTestContext("GET", "/", "127.0.0.1")
+	c.Request.RemoteAddr = "[2001:db8:1:1::1]:1234"
+	if err := m.PreDispatch(c); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	c = newTestContext("GET", "/", "127.0.0.1")
+	c.Request.RemoteAddr = "[2001:db8:1:1::2]:1234"
+	if err := m.PreDispatch(c); err == nil {
+		t.Error("Per /64 cap exceeded")
+	}
+}
+
+func TestConcurrencyLimiterQueue(t *testing.T) {
+	m := NewConcurrencyLimiter(1, 0)
+	m.Queue = 1
+	m.QueueTimeout = time.Second
+	m.RejectCode = 503
+
+	c1 := newTestContext("GET", "/", "10.0.0.1")
+	m.PreDispatch(c1)
+
+	done := make(chan error)
+	go func() {
+		done <- m.PreDispatch(newTestContext("GET", "/", "10.0.0.2"))
+	}()
+
+	// Wait for the request to be queued
+	for {
+		m.Lock()
+		waiting := m.waiting
+		m.Unlock()
+		if waiting == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Queue full
+	err := m.PreDispatch(newTestContext("GET", "/", "10.0.0.3"))
+	if err == nil || err.(*ConcurrencyError).Code() != 503 {
+		t.Error("Full queue didn't reject with 503")
+	}
+
+	m.End(c1)
+	if err := <-done; err != nil {
+		t.Error("Queued request not dispatched after release")
+	}
+
+	// Timeout
+	m.QueueTimeout = 10 * time.Millisecond
+	if err := m.PreDispatch(newTestContext("GET", "/", "10.0.0.4")); err == nil {
+		t.Error("Queued request didn't time out")
+	}
+}
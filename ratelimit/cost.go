@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"github.com/yarf-framework/yarf"
+	"strconv"
+	"strings"
+)
+
+// CostFunc returns how many events a request counts as, so expensive requests use more of the limit.
+// Requests with a cost of 0 or less aren't counted.
+type CostFunc func(c *yarf.Context) int
+
+// MethodCost returns the CostFunc using the cost set for the request method, like {"POST": 5}.
+// Methods not in the map cost 1.
+func MethodCost(costs map[string]int) CostFunc {
+	return func(c *yarf.Context) int {
+		if n, ok := costs[strings.ToUpper(c.Request.Method)]; ok {
+			return n
+		}
+
+		return 1
+	}
+}
+
+// PathCost returns the CostFunc using the cost set for the first path pattern matching the request path.
+// Patterns are the same as the Rule's Path, like {"/search": 10, "/export/*": 50}.
+// Exact paths are checked first, and paths not matching any pattern cost 1.
+func PathCost(costs map[string]int) CostFunc {
+	return func(c *yarf.Context) int {
+		p := c.Request.URL.Path
+		if n, ok := costs[p]; ok {
+			return n
+		}
+
+		// Longest matching pattern, for a deterministic result
+		cost, best := 1, -1
+		for pattern, n := range costs {
+			if len(pattern) > best && matchPath(pattern, p) {
+				cost, best = n, len(pattern)
+			}
+		}
+
+		return cost
+	}
+}
+
+// QueryCost returns the CostFunc that costs per units requested in a query parameter, like "limit" for page sizes.
+// Each started per units cost 1, so with per 100, "?limit=250" costs 3. Missing or invalid values cost 1.
+func QueryCost(param string, per int) CostFunc {
+	if per <= 0 {
+		per = 1
+	}
+
+	return func(c *yarf.Context) int {
+		v, err := strconv.Atoi(c.Request.URL.Query().Get(param))
+		if err != nil || v <= per {
+			return 1
+		}
+
+		return (v + per - 1) / per
+	}
+}
+
+// SizeCost returns the CostFunc that costs per bytes of the request body, using its Content-Length.
+// Each started per bytes cost 1, and requests with no or unknown size cost 1.
+func SizeCost(per int64) CostFunc {
+	if per <= 0 {
+		per = 1
+	}
+
+	return func(c *yarf.Context) int {
+		size := c.Request.ContentLength
+		if size <= per {
+			return 1
+		}
+
+		return int((size + per - 1) / per)
+	}
+}
+
+// MaxCost returns the CostFunc using the highest cost of the given ones, or 1 if none is given.
+func MaxCost(costs ...CostFunc) CostFunc {
+	return func(c *yarf.Context) int {
+		max := 1
+		for i, f := range costs {
+			if n := f(c); i == 0 || n > max {
+				max = n
+			}
+		}
+
+		return max
+	}
+}
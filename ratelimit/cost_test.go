@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCountN(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	for name, a := range map[string]Algorithm{
+		"fixed":   FixedWindow(10, time.Minute),
+		"bucket":  TokenBucket(1, 10),
+		"sliding": SlidingWindow(10, time.Minute),
+	} {
+		rl := NewWithAlgorithm(context.Background(), a)
+		rl.Clock = clock
+
+		if err := rl.CountN("key", 7); err != nil {
+			t.Errorf("%s: cost within the limit blocked: %s", name, err.Error())
+		}
+		if err := rl.CountN("key", 4); err == nil {
+			t.Errorf("%s: cost over the limit allowed", name)
+		}
+		if err := rl.CountN("key", 3); err != nil {
+			t.Errorf("%s: blocked cost was counted: %s", name, err.Error())
+		}
+		if _, remaining, _ := rl.Get("key").Status(); remaining != 0 {
+			t.Errorf("%s: expected 0 remaining, got %d", name, remaining)
+		}
+
+		rl.Close()
+	}
+
+	rl := NewWithBackend(context.Background(), NewMemoryBackend(), 10, time.Minute)
+	defer rl.Close()
+	if err := rl.CountN("key", 10); err != nil {
+		t.Errorf("backend: cost within the limit blocked: %s", err.Error())
+	}
+	if err := rl.CountN("key", 1); err == nil {
+		t.Error("backend: cost over the limit allowed")
+	}
+}
+
+func TestCostFuncs(t *testing.T) {
+	c := newTestContext("POST", "/export/users?limit=250", "10.0.0.1")
+	c.Request.ContentLength = 2500
+
+	if n := MethodCost(map[string]int{"POST": 5})(c); n != 5 {
+		t.Errorf("MethodCost: %d", n)
+	}
+	if n := MethodCost(map[string]int{"DELETE": 5})(c); n != 1 {
+		t.Errorf("MethodCost default: %d", n)
+	}
+	if n := PathCost(map[string]int{"/*": 2, "/export/*": 50})(c); n != 50 {
+		t.Errorf("PathCost: %d", n)
+	}
+	if n := PathCost(map[string]int{"/search": 10})(c); n != 1 {
+		t.Errorf("PathCost default: %d", n)
+	}
+	if n := QueryCost("limit", 100)(c); n != 3 {
+		t.Errorf("QueryCost: %d", n)
+	}
+	if n := QueryCost("page", 100)(c); n != 1 {
+		t.Errorf("QueryCost missing: %d", n)
+	}
+	if n := SizeCost(1024)(c); n != 3 {
+		t.Errorf("SizeCost: %d", n)
+	}
+	if n := MaxCost(SizeCost(1024), MethodCost(map[string]int{"POST": 5}))(c); n != 5 {
+		t.Errorf("MaxCost: %d", n)
+	}
+}
+
+func TestMiddlewareCost(t *testing.T) {
+	m := YarfMiddleware(10, 60)
+	defer m.Close()
+	m.Cost = MethodCost(map[string]int{"POST": 4, http.MethodOptions: 0})
+
+	c := newTestContext("POST", "/", "10.0.0.1")
+	if err := m.PreDispatch(c); err != nil {
+		t.Fatal(err.Error())
+	}
+	if r := c.Response.Header().Get("RateLimit-Remaining"); r != "6" {
+		t.Errorf("Expected 6 remaining, got %s", r)
+	}
+
+	// Not counted
+	c = newTestContext("OPTIONS", "/", "10.0.0.1")
+	m.PreDispatch(c)
+	if r := c.Response.Header().Get("RateLimit-Remaining"); r != "" {
+		t.Errorf("Free request counted: %s remaining", r)
+	}
+
+	m.PreDispatch(newTestContext("POST", "/", "10.0.0.1"))
+	if err := m.PreDispatch(newTestContext("POST", "/", "10.0.0.1")); err == nil {
+		t.Error("Cost over the limit allowed")
+	}
+
+	c = newTestContext("GET", "/", "10.0.0.1")
+	if err := m.PreDispatch(c); err != nil {
+		t.Errorf("Request within the remaining limit blocked: %s", err.Error())
+	}
+	if r := c.Response.Header().Get("RateLimit-Remaining"); r != "1" {
+		t.Errorf("Expected 1 remaining, got %s", r)
+	}
+}
+
+func TestDelayN(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	for name, a := range map[string]Algorithm{
+		"fixed":   FixedWindow(10, time.Minute),
+		"bucket":  TokenBucket(1, 10),
+		"sliding": SlidingWindow(10, time.Minute),
+	} {
+		l := a(clock)
+		l.CountN(1)
+
+		if d := l.DelayN(9); d != 0 {
+			t.Errorf("%s: expected no delay for 9 events, got %s", name, d)
+		}
+		if d := l.DelayN(10); d <= 0 {
+			t.Errorf("%s: expected a delay for 10 events", name)
+		}
+		if err := l.CountN(11); err != (CostError{}) {
+			t.Errorf("%s: expected a CostError over the limit, got %v", name, err)
+		}
+	}
+}
+
+func TestMiddlewareCostRetryAfter(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	rl := NewWithWindow(context.Background(), 10, time.Minute)
+	rl.Clock = clock
+	m := NewRateLimiter(rl)
+	defer m.Close()
+	m.Cost = QueryCost("cost", 1)
+
+	m.PreDispatch(newTestContext("GET", "/", "10.0.0.1"))
+	m.PreDispatch(newTestContext("GET", "/?cost=5", "10.0.0.1"))
+
+	c := newTestContext("GET", "/?cost=9", "10.0.0.1")
+	if err := m.PreDispatch(c); err == nil {
+		t.Fatal("Cost over the remaining limit allowed")
+	}
+	if h := c.Response.Header().Get("Retry-After"); h != "60" {
+		t.Errorf("Expected Retry-After 60, got %s", h)
+	}
+
+	// Never allowed
+	c = newTestContext("GET", "/?cost=11", "10.0.0.1")
+	err := m.PreDispatch(c)
+	if ye, ok := err.(CostError); !ok || ye.Code() != 400 {
+		t.Errorf("Expected a CostError, got %v", err)
+	}
+	if h := c.Response.Header().Get("Retry-After"); h != "" {
+		t.Errorf("Retry-After set for a cost over the limit: %s", h)
+	}
+}
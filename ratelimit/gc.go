@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// collector runs a sweep function periodically on its own goroutine,
+// until it's closed or its context is cancelled.
+type collector struct {
+	// Cancel function of the running goroutine
+	cancel context.CancelFunc
+
+	// Closed when the goroutine stops
+	done chan struct{}
+
+	// Set after close, prevents the goroutine from starting again
+	closed bool
+
+	// Set to 1 once started or closed, so start doesn't take the lock on every call.
+	state uint32
+
+	// Sync Mutex
+	sync.Mutex
+}
+
+// start runs the collector if it isn't running yet.
+func (gc *collector) start(ctx context.Context, clock Clock, interval time.Duration, sweep func()) {
+	if gc.started() {
+		return
+	}
+
+	gc.Lock()
+	defer gc.Unlock()
+
+	if gc.done != nil || gc.closed {
+		return
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx, gc.cancel = context.WithCancel(ctx)
+	gc.done = make(chan struct{})
+
+	// Create the ticker now, so clock changes after start are seen by it.
+	t := clockOrSystem(clock).NewTicker(interval)
+
+	go gc.run(ctx, gc.done, t, sweep)
+
+	atomic.StoreUint32(&gc.state, 1)
+}
+
+// started reports if the collector has been started or closed already, without locking.
+func (gc *collector) started() bool {
+	return atomic.LoadUint32(&gc.state) == 1
+}
+
+func (gc *collector) run(ctx context.Context, done chan struct{}, t Ticker, sweep func()) {
+	defer close(done)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-t.Chan():
+			sweep()
+		}
+	}
+}
+
+// close stops the goroutine and waits for it to return.
+func (gc *collector) close() {
+	gc.Lock()
+	gc.closed = true
+	atomic.StoreUint32(&gc.state, 1)
+	cancel, done := gc.cancel, gc.done
+	gc.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
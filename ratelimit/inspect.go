@@ -0,0 +1,251 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"github.com/yarf-framework/yarf"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// KeyState is the rate limit state of a counted key, to find out why a client is being blocked.
+type KeyState struct {
+	// Name of the rule or layer counting the key, empty for the default limit of a RateLimiter.
+	Rule string `json:"rule,omitempty"`
+
+	// Counted key
+	Key string `json:"key"`
+
+	// Events allowed
+	Limit int `json:"limit"`
+
+	// Events still allowed
+	Remaining int `json:"remaining"`
+
+	// Time when the full limit is available again
+	Reset time.Time `json:"reset"`
+
+	// Seconds until a new event is allowed, 0 if it's allowed now.
+	RetryAfter int `json:"retry_after"`
+
+	// Allowed counts since the key started being counted
+	Allowed uint64 `json:"allowed"`
+
+	// Blocked counts since the key started being counted
+	Blocked uint64 `json:"blocked"`
+
+	// Ban in force for the key, if any
+	Ban *Ban `json:"ban,omitempty"`
+}
+
+// state returns the state of an entry.
+func (e *entry) state(key string) KeyState {
+	s := KeyState{
+		Key:        key,
+		RetryAfter: seconds(e.limiter.Delay()),
+		Allowed:    e.allowed.Load(),
+		Blocked:    e.blocked.Load(),
+	}
+	s.Limit, s.Remaining, s.Reset = e.limiter.Status()
+
+	return s
+}
+
+// State returns the state of a key, and false if the key isn't being counted.
+func (rl *RateLimit) State(key string) (KeyState, bool) {
+	e, ok := rl.counter.lookup(key)
+	if !ok {
+		return KeyState{}, false
+	}
+
+	return e.state(key), true
+}
+
+// Top returns the states of the n keys with the most blocked counts, from the most blocked.
+// Keys never blocked aren't returned.
+func (rl *RateLimit) Top(n int) []KeyState {
+	type blocked struct {
+		key string
+		e   *entry
+	}
+
+	var list []blocked
+	rl.counter.each(func(key string, e *entry) {
+		if e.blocked.Load() > 0 {
+			list = append(list, blocked{key, e})
+		}
+	})
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].e.blocked.Load() > list[j].e.blocked.Load()
+	})
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+
+	states := make([]KeyState, len(list))
+	for i, b := range list {
+		states[i] = b.e.state(b.key)
+	}
+
+	return states
+}
+
+// tally counts the requests allowed and blocked by a RateLimiter limit.
+type tally struct {
+	allowed, blocked atomic.Uint64
+}
+
+// record adds a verdict to the tally.
+func (t *tally) record(v verdict) {
+	switch {
+	case v.status == nil:
+		// Not counted
+
+	case v.reason == "":
+		t.allowed.Add(1)
+
+	default:
+		t.blocked.Add(1)
+	}
+}
+
+// RuleTotals are the requests allowed and blocked by a rule of a RateLimiter since it started.
+// Requests reported in Shadow mode count as blocked.
+type RuleTotals struct {
+	// Name of the rule, empty for the default limit.
+	Rule string `json:"rule"`
+
+	// Requests allowed
+	Allowed uint64 `json:"allowed"`
+
+	// Requests blocked, by their limit or by a ban
+	Blocked uint64 `json:"blocked"`
+}
+
+// Totals returns the requests allowed and blocked by the default limit, if any, and by each rule.
+func (m *RateLimiter) Totals() []RuleTotals {
+	var totals []RuleTotals
+
+	if m.rl != nil {
+		totals = append(totals, RuleTotals{
+			Allowed: m.tally.allowed.Load(),
+			Blocked: m.tally.blocked.Load(),
+		})
+	}
+
+	for _, r := range m.Rules {
+		totals = append(totals, RuleTotals{
+			Rule:    r.Name,
+			Allowed: r.tally.allowed.Load(),
+			Blocked: r.tally.blocked.Load(),
+		})
+	}
+
+	return totals
+}
+
+// limits calls f for the default limit, the limit of each rule and their layers.
+// Layers are named after their rule, like "api/48" for the layer "48" of the rule "api".
+func (m *RateLimiter) limits(f func(rule string, rl *RateLimit)) {
+	if m.rl != nil {
+		f("", m.rl)
+	}
+	for _, l := range m.Layers {
+		if l.Limit != nil {
+			f(l.Name, l.Limit)
+		}
+	}
+
+	for _, r := range m.Rules {
+		if r.Limit != nil {
+			f(r.Name, r.Limit)
+		}
+
+		for _, l := range r.Layers {
+			if l.Limit != nil {
+				f(r.Name+"/"+l.Name, l.Limit)
+			}
+		}
+	}
+}
+
+// Inspect returns the state of a key on every limit counting it, including its ban if any.
+func (m *RateLimiter) Inspect(key string) []KeyState {
+	var ban *Ban
+	if m.Penalty != nil {
+		if b, ok := m.Penalty.Banned(key); ok {
+			ban = &b
+		}
+	}
+
+	states := make([]KeyState, 0)
+	m.limits(func(rule string, rl *RateLimit) {
+		if s, ok := rl.State(key); ok {
+			s.Rule, s.Ban = rule, ban
+			states = append(states, s)
+		}
+	})
+
+	return states
+}
+
+// Top returns the states of the n keys with the most blocked counts on all limits, from the most blocked.
+func (m *RateLimiter) Top(n int) []KeyState {
+	states := make([]KeyState, 0)
+	m.limits(func(rule string, rl *RateLimit) {
+		for _, s := range rl.Top(n) {
+			s.Rule = rule
+			states = append(states, s)
+		}
+	})
+
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].Blocked > states[j].Blocked
+	})
+	if n > 0 && len(states) > n {
+		states = states[:n]
+	}
+
+	return states
+}
+
+// Inspector is a Yarf resource exposing the introspection of a RateLimiter as JSON, for admin dashboards.
+// It has no access control: route it behind an auth middleware.
+//
+// GET with a "key" query parameter returns the states of the key, like Inspect.
+// Otherwise it returns the rule totals and the "top" (defaults to 10) most blocked keys.
+type Inspector struct {
+	yarf.Resource
+
+	// Inspected middleware
+	Limiter *RateLimiter
+}
+
+// Get renders the introspection data.
+func (i *Inspector) Get(c *yarf.Context) error {
+	q := c.Request.URL.Query()
+
+	var data interface{}
+	if key := q.Get("key"); key != "" {
+		data = map[string]interface{}{
+			"key":    key,
+			"states": i.Limiter.Inspect(key),
+		}
+	} else {
+		n, err := strconv.Atoi(q.Get("top"))
+		if err != nil || n <= 0 {
+			n = 10
+		}
+
+		data = map[string]interface{}{
+			"rules": i.Limiter.Totals(),
+			"top":   i.Limiter.Top(n),
+		}
+	}
+
+	c.Response.Header().Set("Content-Type", "application/json")
+
+	return json.NewEncoder(c.Response).Encode(data)
+}
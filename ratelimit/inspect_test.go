@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitState(t *testing.T) {
+	rl := New(2, 60)
+	defer rl.Close()
+
+	for i := 0; i < 5; i++ {
+		rl.Count("busy")
+	}
+	rl.Count("calm")
+	rl.Count("blocked")
+	rl.Count("blocked")
+	rl.Count("blocked")
+
+	if _, ok := rl.State("unknown"); ok {
+		t.Error("State of a key not counted")
+	}
+
+	s, ok := rl.State("busy")
+	if !ok {
+		t.Fatal("Missing state")
+	}
+	if s.Allowed != 2 || s.Blocked != 3 || s.Remaining != 0 || s.Limit != 2 || s.RetryAfter <= 0 {
+		t.Errorf("Unexpected state %+v", s)
+	}
+
+	top := rl.Top(10)
+	if len(top) != 2 || top[0].Key != "busy" || top[1].Key != "blocked" {
+		t.Errorf("Unexpected top %+v", top)
+	}
+	if top := rl.Top(1); len(top) != 1 {
+		t.Errorf("Expected 1 key, got %d", len(top))
+	}
+}
+
+func TestRateLimiterIntrospection(t *testing.T) {
+	m := YarfMiddleware(1, 60)
+	m.Rules = []*Rule{{Name: "login", Path: "/login", Limit: New(1, 60)}}
+	m.Penalty = NewPenalty(time.Minute)
+	defer m.Close()
+
+	m.PreDispatch(newTestContext("GET", "/", "10.0.0.1"))
+	m.PreDispatch(newTestContext("GET", "/", "10.0.0.1"))
+	m.PreDispatch(newTestContext("POST", "/login", "10.0.0.2"))
+
+	totals := m.Totals()
+	if len(totals) != 2 || totals[0].Allowed != 1 || totals[0].Blocked != 1 || totals[1].Rule != "login" || totals[1].Allowed != 1 {
+		t.Errorf("Unexpected totals %+v", totals)
+	}
+
+	states := m.Inspect("10.0.0.1")
+	if len(states) != 1 || states[0].Rule != "" || states[0].Ban == nil {
+		t.Errorf("Unexpected states %+v", states)
+	}
+
+	// Resource
+	c := newTestContext("GET", "/admin/ratelimit?top=5", "10.0.0.9")
+	rec := httptest.NewRecorder()
+	c.Response = rec
+
+	if err := (&Inspector{Limiter: m}).Get(c); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var data struct {
+		Rules []RuleTotals
+		Top   []KeyState
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &data); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(data.Rules) != 2 || len(data.Top) != 1 || data.Top[0].Key != "10.0.0.1" {
+		t.Errorf("Unexpected response %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/yarf-framework/extras/auth"
+	"github.com/yarf-framework/extras/realip"
+	"github.com/yarf-framework/yarf"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// KeyFunc extracts the key used to count a request.
+// Requests with an empty key aren't limited.
+//
+// Built-in extractors other than ClientIP prefix their keys with a "name=" to avoid collisions between them.
+type KeyFunc func(c *yarf.Context) string
+
+// ClientIP uses the client IP address as key, counting each IPv6 address on its own.
+// It's the address resolved by the realip middleware, or the connection remote address, never a client header.
+// Insert the realip middleware before the rate limiter to get the address of clients behind trusted proxies.
+func ClientIP(c *yarf.Context) string {
+	return realip.Verified(c)
+}
+
+// ClientNet returns a KeyFunc that groups the client IPs by network, like "2001:db8:1:2::/64",
+// so a client can't bypass its limit by rotating addresses within its network.
+// IPv4 addresses are grouped by the v4 prefix length, like 24, and IPv6 addresses by the v6 one, like 64.
+// A prefix length of 0 or the full address length keeps the addresses as they are.
+func ClientNet(v4, v6 int) KeyFunc {
+	return func(c *yarf.Context) string {
+		return network(ClientIP(c), v4, v6)
+	}
+}
+
+// ClientNet64 groups IPv6 clients by /64, as each one usually gets a whole /64, and keeps the IPv4 addresses.
+// It's the default KeyFunc.
+var ClientNet64 = ClientNet(0, 64)
+
+// network returns the network of an IP address as a CIDR, or the address itself if it isn't grouped.
+func network(s string, v4, v6 int) string {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return s
+	}
+
+	bits, prefix := 128, v6
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits, prefix = ip4, 32, v4
+	}
+	if prefix <= 0 || prefix >= bits {
+		return ip.String()
+	}
+
+	return ip.Mask(net.CIDRMask(prefix, bits)).String() + "/" + strconv.Itoa(prefix)
+}
+
+// Path uses the request path as key, to limit routes instead of clients.
+func Path(c *yarf.Context) string {
+	return "path=" + c.Request.URL.Path
+}
+
+// AuthToken uses the token sent on the request, as read by auth.GetToken, as key.
+// Only tokens that pass auth.ValidateToken are used, so clients can't skip their limits sending random tokens.
+// The token is hashed so it doesn't get exposed by the key.
+func AuthToken(c *yarf.Context) string {
+	token := auth.GetToken(c.Request)
+	if token == "" {
+		return ""
+	}
+	if _, err := auth.ValidateToken(token); err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("token=%x", sha256.Sum256([]byte(token)))
+}
+
+// AuthData uses the "_authData" value set by the auth middleware, usually the user ID, as key.
+// The auth middleware has to be inserted before the rate limiter.
+func AuthData(c *yarf.Context) string {
+	if c.Data == nil {
+		return ""
+	}
+
+	data, err := c.Data.Get("_authData")
+	if err != nil {
+		return ""
+	}
+
+	if s, ok := data.(string); ok && s != "" {
+		return "auth=" + s
+	}
+
+	return ""
+}
+
+// Header returns a KeyFunc that uses the value of a request header, like an API key, as key.
+// Only the values accepted by valid are used, like API keys found on a store, so clients can't skip their limits
+// sending random values. Other values, or all of them when valid is nil, give an empty key.
+func Header(name string, valid func(string) bool) KeyFunc {
+	return func(c *yarf.Context) string {
+		v := c.Request.Header.Get(name)
+		if v == "" || valid == nil || !valid(v) {
+			return ""
+		}
+
+		return name + "=" + v
+	}
+}
+
+// Combine returns a KeyFunc that joins the keys of all the given KeyFuncs, like the client IP and the path.
+// If any of them is empty, the combined key is empty too.
+func Combine(fns ...KeyFunc) KeyFunc {
+	return func(c *yarf.Context) string {
+		keys := make([]string, len(fns))
+		for i, fn := range fns {
+			keys[i] = fn(c)
+			if keys[i] == "" {
+				return ""
+			}
+		}
+
+		return strings.Join(keys, "|")
+	}
+}
+
+// First returns a KeyFunc that uses the first non empty key of the given KeyFuncs.
+// It allows fallbacks like First(Header("X-Api-Key", apiKeys.Valid), ClientNet64) to limit per API key and anonymous clients per IP.
+func First(fns ...KeyFunc) KeyFunc {
+	return func(c *yarf.Context) string {
+		for _, fn := range fns {
+			if key := fn(c); key != "" {
+				return key
+			}
+		}
+
+		return ""
+	}
+}
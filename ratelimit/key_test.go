@@ -0,0 +1,208 @@
+package ratelimit
+
+import (
+	"github.com/yarf-framework/extras/auth"
+	"github.com/yarf-framework/extras/context/data"
+	"github.com/yarf-framework/extras/realip"
+	"github.com/yarf-framework/yarf"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newTestContext(method, path, ip string) *yarf.Context {
+	r, _ := http.NewRequest(method, path, nil)
+	r.RemoteAddr = ip + ":1234"
+
+	return yarf.NewContext(r, httptest.NewRecorder())
+}
+
+func validKey(key string) bool {
+	return key == "partner"
+}
+
+func TestKeyFuncs(t *testing.T) {
+	c := newTestContext("GET", "/api/items", "10.0.0.1")
+	c.Request.Header.Set("X-Api-Key", "partner")
+
+	if k := ClientIP(c); k != "10.0.0.1" {
+		t.Errorf("ClientIP: %s", k)
+	}
+	if k := Path(c); k != "path=/api/items" {
+		t.Errorf("Path: %s", k)
+	}
+	if k := Header("X-Api-Key", validKey)(c); k != "X-Api-Key=partner" {
+		t.Errorf("Header: %s", k)
+	}
+	if k := Header("X-Api-Key", nil)(c); k != "" {
+		t.Errorf("Header without validator: %s", k)
+	}
+	c.Request.Header.Set("X-Api-Key", "random")
+	if k := First(Header("X-Api-Key", validKey), ClientIP)(c); k != "10.0.0.1" {
+		t.Errorf("Header with an invalid value: %s", k)
+	}
+	c.Request.Header.Set("X-Api-Key", "partner")
+	if k := Header("X-Api-Key", validKey)(c); k != "X-Api-Key=partner" {
+		t.Errorf("Header: %s", k)
+	}
+	if k := Combine(ClientIP, Path)(c); k != "10.0.0.1|path=/api/items" {
+		t.Errorf("Combine: %s", k)
+	}
+	if k := Combine(ClientIP, Header("Missing", validKey))(c); k != "" {
+		t.Errorf("Combine with empty key: %s", k)
+	}
+	if k := First(Header("Missing", validKey), ClientIP)(c); k != "10.0.0.1" {
+		t.Errorf("First: %s", k)
+	}
+	if k := AuthToken(c); k != "" {
+		t.Errorf("AuthToken without token: %s", k)
+	}
+
+	c.Request.Header.Set("Auth", "random")
+	if k := AuthToken(c); k != "" {
+		t.Errorf("AuthToken with an invalid token: %s", k)
+	}
+
+	token := auth.NewToken("user-1", 60)
+	defer auth.DeleteToken(token)
+	c.Request.Header.Set("Auth", token)
+	if k := AuthToken(c); !strings.HasPrefix(k, "token=") {
+		t.Errorf("AuthToken with a valid token: %s", k)
+	}
+}
+
+func TestClientIPRealIP(t *testing.T) {
+	c := newTestContext("GET", "/", "10.0.0.1")
+	c.Request.Header.Set("X-Forwarded-For", "1.2.3.4")
+	c.Data = new(data.StrData)
+
+	rip, _ := realip.YarfMiddleware("10.0.0.0/8")
+	rip.PreDispatch(c)
+	if k := ClientIP(c); k != "1.2.3.4" {
+		t.Errorf("ClientIP behind a trusted proxy: %s", k)
+	}
+
+	c = newTestContext("GET", "/", "203.0.113.7")
+	c.Request.Header.Set("X-Forwarded-For", "1.2.3.4")
+	c.Data = new(data.StrData)
+
+	rip.PreDispatch(c)
+	if k := ClientIP(c); k != "203.0.113.7" {
+		t.Errorf("ClientIP with a spoofed header: %s", k)
+	}
+}
+
+func TestRateLimiterKey(t *testing.T) {
+	m := YarfMiddleware(1, 60)
+	defer m.Close()
+	m.Key = Header("X-Api-Key", validKey)
+
+	// Empty keys aren't limited
+	for i := 0; i < 3; i++ {
+		if err := m.PreDispatch(newTestContext("GET", "/", "10.0.0.1")); err != nil {
+			t.Fatal("Request without key limited")
+		}
+	}
+
+	c := newTestContext("GET", "/", "10.0.0.1")
+	c.Request.Header.Set("X-Api-Key", "partner")
+	if err := m.PreDispatch(c); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := m.PreDispatch(c); err == nil {
+		t.Error("Limit exceeded")
+	}
+}
+
+func TestClientNet(t *testing.T) {
+	for _, tc := range []struct {
+		ip     string
+		v4, v6 int
+		key    string
+	}{
+		{"2001:db8:1:2:3:4:5:6", 0, 64, "2001:db8:1:2::/64"},
+		{"2001:db8:1:2:3:4:5:6", 0, 48, "2001:db8:1::/48"},
+		{"2001:db8:1:2:3:4:5:6", 0, 0, "2001:db8:1:2:3:4:5:6"},
+		{"203.0.113.7", 0, 64, "203.0.113.7"},
+		{"203.0.113.7", 24, 64, "203.0.113.0/24"},
+		{"::ffff:203.0.113.7", 24, 64, "203.0.113.0/24"},
+	} {
+		c := newTestContext("GET", "/", "127.0.0.1")
+		c.Request.RemoteAddr = net.JoinHostPort(tc.ip, "1234")
+
+		if k := ClientNet(tc.v4, tc.v6)(c); k != tc.key {
+			t.Errorf("%s /%d /%d: expected %s, got %s", tc.ip, tc.v4, tc.v6, tc.key, k)
+		}
+	}
+}
+
+func TestClientIPSpoofed(t *testing.T) {
+	m := YarfMiddleware(1, 60)
+	defer m.Close()
+
+	// A new X-Forwarded-For value on every request doesn't get a new key.
+	var err error
+	for i := 0; i < 2; i++ {
+		c := newTestContext("GET", "/", "198.51.100.7")
+		c.Request.Header.Set("X-Forwarded-For", "10.0.0."+strconv.Itoa(i))
+		err = m.PreDispatch(c)
+	}
+	if err == nil {
+		t.Error("Spoofed header bypassed the limit")
+	}
+}
+
+func TestClientIPKey(t *testing.T) {
+	m := NewRateLimiter(New(1, 60))
+	defer m.Close()
+	m.Key = ClientIP
+
+	for _, ip := range []string{"2001:db8:1:1::1", "2001:db8:1:1::2"} {
+		c := newTestContext("GET", "/", "127.0.0.1")
+		c.Request.RemoteAddr = net.JoinHostPort(ip, "1234")
+		if err := m.PreDispatch(c); err != nil {
+			t.Errorf("Address %s limited by another one", ip)
+		}
+	}
+}
+
+func TestLayers(t *testing.T) {
+	// Default key: ClientNet64
+	m := NewRateLimiter(New(2, 60))
+	defer m.Close()
+	m.Layers = []*Layer{{Name: "48", Limit: New(3, 60), Key: ClientNet(0, 48)}}
+
+	request := func(ip string) (string, error) {
+		c := newTestContext("GET", "/", "127.0.0.1")
+		c.Request.RemoteAddr = net.JoinHostPort(ip, "1234")
+		err := m.PreDispatch(c)
+
+		return c.Response.Header().Get("RateLimit-Remaining"), err
+	}
+
+	// Rotating addresses within the /64
+	if remaining, err := request("2001:db8:1:1::1"); err != nil || remaining != "1" {
+		t.Fatalf("Unexpected result %v, %s remaining", err, remaining)
+	}
+	if _, err := request("2001:db8:1:1::2"); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := request("2001:db8:1:1::3"); err == nil {
+		t.Error("Per /64 limit exceeded")
+	}
+
+	// Another /64 within the same /48 is limited by the layer, which sets the headers.
+	if remaining, err := request("2001:db8:1:2::1"); err != nil || remaining != "0" {
+		t.Fatalf("Unexpected result %v, %s remaining", err, remaining)
+	}
+	if _, err := request("2001:db8:1:3::1"); err == nil {
+		t.Error("Per /48 limit exceeded")
+	}
+
+	if states := m.Inspect("2001:db8:1::/48"); len(states) != 1 || states[0].Rule != "48" {
+		t.Errorf("Unexpected layer states %+v", states)
+	}
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// Limiter is implemented by the rate limiting algorithms to count the events of a single key.
+type Limiter interface {
+	// Count registers an event.
+	// If the limit has been reached it returns a RateLimitError error.
+	Count() error
+
+	// CountN registers n events at once, all or none.
+	// If they aren't allowed it returns a RateLimitError error,
+	// and if n is over the limit, so they can never be allowed, a CostError error.
+	CountN(n int) error
+
+	// Status returns the limit, the amount of events still allowed and the time when the full limit is available again.
+	Status() (limit, remaining int, reset time.Time)
+
+	// Expired reports if the Limiter has no state worth keeping at a given time, so it can be garbage collected.
+	Expired(t time.Time) bool
+
+	// Delay returns how long until the next event is allowed, 0 if it's allowed now.
+	Delay() time.Duration
+
+	// DelayN returns how long until n events at once are allowed, 0 if they're allowed now.
+	// n must not be over the limit.
+	DelayN(n int) time.Duration
+}
+
+// Algorithm creates a new Limiter for each key counted by a RateLimit, using the RateLimit's Clock.
+// The clock can be nil, meaning SystemClock.
+type Algorithm func(clock Clock) Limiter
+
+// FixedWindow returns the Algorithm that allows limit events within fixed windows.
+// This is the default Algorithm used by RateLimit.
+func FixedWindow(limit int, window time.Duration) Algorithm {
+	return func(clock Clock) Limiter {
+		return &Rate{
+			Limit:      limit,
+			Window:     window,
+			EventCount: 0,
+			Start:      now(clock),
+			Clock:      clock,
+		}
+	}
+}
+
+// TokenBucket returns the Algorithm that allows bursts of up to burst events, refilled at rate events per second.
+func TokenBucket(rate float64, burst int) Algorithm {
+	return func(clock Clock) Limiter {
+		b := NewBucket(rate, burst)
+		b.Clock = clock
+		b.Last = now(clock)
+
+		return b
+	}
+}
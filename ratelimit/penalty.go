@@ -0,0 +1,195 @@
+package ratelimit
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultDurations are the ban durations of a Penalty without Durations.
+var defaultDurations = []time.Duration{time.Minute, 10 * time.Minute, time.Hour}
+
+// Ban is the penalty state of a key.
+type Ban struct {
+	// Banned key
+	Key string `json:"key"`
+
+	// Times the key exceeded its limit
+	Offenses int `json:"offenses"`
+
+	// Last time the key exceeded its limit
+	LastOffense time.Time `json:"last_offense"`
+
+	// Ban end
+	Until time.Time `json:"until"`
+}
+
+// Active reports if the ban is in force at a given time.
+func (b Ban) Active(t time.Time) bool {
+	return t.Before(b.Until)
+}
+
+// Penalty bans the keys that exceed their limit, for longer each time they do it again, like fail2ban.
+// Set it on a RateLimiter to ban the keys blocked by its limits.
+type Penalty struct {
+	// Ban durations for each offense. Offenses beyond the list get the last one.
+	// Defaults to 1 minute, 10 minutes and 1 hour.
+	Durations []time.Duration
+
+	// Time without new offenses to forget the previous ones. Defaults to 24 hours.
+	Forget time.Duration
+
+	// Garbage collector sweep interval. Defaults to 1 minute.
+	SweepInterval time.Duration
+
+	// Time source for the bans and the garbage collector. Defaults to SystemClock.
+	Clock Clock
+
+	// Bans storage
+	bans map[string]Ban
+
+	// Garbage collector parent context
+	ctx context.Context
+
+	// Garbage collector
+	gc collector
+
+	// Sync Mutex
+	sync.RWMutex
+}
+
+// NewPenalty creates a Penalty with the given ban durations for each offense.
+// Defaults to 1 minute, 10 minutes and 1 hour when no durations are given.
+func NewPenalty(durations ...time.Duration) *Penalty {
+	return NewPenaltyWithContext(context.Background(), durations...)
+}
+
+// NewPenaltyWithContext creates a Penalty whose garbage collector stops when ctx is cancelled.
+func NewPenaltyWithContext(ctx context.Context, durations ...time.Duration) *Penalty {
+	if len(durations) == 0 {
+		durations = append([]time.Duration(nil), defaultDurations...)
+	}
+
+	return &Penalty{
+		Durations: durations,
+		Forget:    24 * time.Hour,
+		bans:      make(map[string]Ban),
+		ctx:       ctx,
+	}
+}
+
+// Offend records an offense of a key and bans it for the duration corresponding to its offenses count.
+// Offenses of keys already banned aren't counted.
+func (p *Penalty) Offend(key string) Ban {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.bans == nil {
+		p.bans = make(map[string]Ban)
+	}
+
+	// Init garbage collector
+	interval := p.SweepInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	p.gc.start(p.ctx, p.Clock, interval, p.Sweep)
+
+	t := now(p.Clock)
+	b, ok := p.bans[key]
+	if ok && b.Active(t) {
+		return b
+	}
+	if !ok || p.forgotten(b, t) {
+		b = Ban{Key: key}
+	}
+
+	b.Offenses++
+	b.LastOffense = t
+
+	durations := p.Durations
+	if len(durations) == 0 {
+		durations = defaultDurations
+	}
+
+	i := b.Offenses - 1
+	if i >= len(durations) {
+		i = len(durations) - 1
+	}
+	b.Until = t.Add(durations[i])
+
+	p.bans[key] = b
+
+	return b
+}
+
+// forgotten reports if the offenses of a ban are old enough to be forgotten at a given time.
+func (p *Penalty) forgotten(b Ban, t time.Time) bool {
+	forget := p.Forget
+	if forget <= 0 {
+		forget = 24 * time.Hour
+	}
+
+	return !b.Active(t) && t.After(b.LastOffense.Add(forget))
+}
+
+// Banned returns the ban of a key and reports if it's in force.
+func (p *Penalty) Banned(key string) (Ban, bool) {
+	p.RLock()
+	defer p.RUnlock()
+
+	b, ok := p.bans[key]
+
+	return b, ok && b.Active(now(p.Clock))
+}
+
+// List returns the bans in force, sorted by end time.
+func (p *Penalty) List() []Ban {
+	p.RLock()
+	defer p.RUnlock()
+
+	t := now(p.Clock)
+	list := make([]Ban, 0)
+	for _, b := range p.bans {
+		if b.Active(t) {
+			list = append(list, b)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Until.Before(list[j].Until)
+	})
+
+	return list
+}
+
+// Lift removes the ban and the offenses of a key.
+func (p *Penalty) Lift(key string) {
+	p.Lock()
+	defer p.Unlock()
+
+	delete(p.bans, key)
+}
+
+// Sweep removes the bans already ended whose offenses are forgotten.
+// It's called periodically by the garbage collector and can be called directly.
+func (p *Penalty) Sweep() {
+	t := now(p.Clock)
+
+	p.Lock()
+	defer p.Unlock()
+
+	for key, b := range p.bans {
+		if p.forgotten(b, t) {
+			delete(p.bans, key)
+		}
+	}
+}
+
+// Close stops the garbage collector and waits for it to return.
+func (p *Penalty) Close() error {
+	p.gc.close()
+
+	return nil
+}
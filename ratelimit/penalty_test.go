@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPenalty(t *testing.T) {
+	p := NewPenalty(time.Minute, 10*time.Minute)
+	defer p.Close()
+
+	b := p.Offend("key")
+	if b.Offenses != 1 || b.Until.Sub(b.LastOffense) != time.Minute {
+		t.Errorf("Unexpected first ban: %+v", b)
+	}
+
+	// Offenses while banned aren't counted
+	if b = p.Offend("key"); b.Offenses != 1 {
+		t.Error("Offense counted while banned")
+	}
+
+	// Ban ended: escalate
+	b.Until = time.Now()
+	p.bans["key"] = b
+	if _, ok := p.Banned("key"); ok {
+		t.Error("Ended ban in force")
+	}
+	if b = p.Offend("key"); b.Offenses != 2 || b.Until.Sub(b.LastOffense) != 10*time.Minute {
+		t.Errorf("Unexpected second ban: %+v", b)
+	}
+
+	// Beyond the list, the last duration
+	b.Until = time.Now()
+	p.bans["key"] = b
+	if b = p.Offend("key"); b.Until.Sub(b.LastOffense) != 10*time.Minute {
+		t.Errorf("Unexpected third ban: %+v", b)
+	}
+
+	if list := p.List(); len(list) != 1 || list[0].Key != "key" {
+		t.Errorf("Unexpected bans list: %v", list)
+	}
+
+	p.Lift("key")
+	if _, ok := p.Banned("key"); ok {
+		t.Error("Lifted ban in force")
+	}
+}
+
+func TestPenaltyDefaultDurations(t *testing.T) {
+	p := new(Penalty)
+	defer p.Close()
+
+	if b := p.Offend("key"); b.Until.Sub(b.LastOffense) != time.Minute {
+		t.Errorf("Unexpected default ban: %+v", b)
+	}
+}
+
+func TestPenaltySweep(t *testing.T) {
+	p := NewPenalty()
+	defer p.Close()
+
+	p.Offend("old")
+	p.Offend("new")
+
+	p.bans["old"] = Ban{Key: "old", Offenses: 3, LastOffense: time.Now().Add(-25 * time.Hour), Until: time.Now().Add(-24 * time.Hour)}
+
+	p.Sweep()
+	if _, ok := p.bans["old"]; ok {
+		t.Error("Forgotten offenses not removed")
+	}
+	if _, ok := p.bans["new"]; !ok {
+		t.Error("Active ban removed")
+	}
+}
+
+func TestRateLimiterPenalty(t *testing.T) {
+	m := YarfMiddleware(1, 60)
+	m.Penalty = NewPenalty(time.Hour)
+	defer m.Close()
+
+	m.PreDispatch(newTestContext("GET", "/", "10.0.0.1"))
+
+	c := newTestContext("GET", "/", "10.0.0.1")
+	if err := m.PreDispatch(c); err == nil {
+		t.Fatal("Limit exceeded")
+	}
+	if c.Response.Header().Get("Retry-After") != "3600" {
+		t.Errorf("Expected ban Retry-After, got %s", c.Response.Header().Get("Retry-After"))
+	}
+	if _, ok := m.Penalty.Banned("10.0.0.1"); !ok {
+		t.Error("Key not banned")
+	}
+}
+
+func TestRateLimiterPenaltyTop(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	m := YarfMiddleware(1, 60)
+	m.rl.Clock = clock
+	m.Penalty = NewPenalty(time.Hour)
+	m.Penalty.Clock = clock
+	defer m.Close()
+
+	for i := 0; i < 3; i++ {
+		m.PreDispatch(newTestContext("GET", "/", "10.0.0.1"))
+	}
+
+	// The window ends, the ban doesn't.
+	clock.Advance(10 * time.Minute)
+	m.rl.Sweep()
+	m.PreDispatch(newTestContext("GET", "/", "10.0.0.1"))
+
+	top := m.Top(1)
+	if len(top) != 1 || top[0].Key != "10.0.0.1" || top[0].Blocked != 3 {
+		t.Errorf("Unexpected top %+v", top)
+	}
+
+	// The ban ends.
+	clock.Advance(time.Hour)
+	m.rl.Sweep()
+	if _, ok := m.rl.State("10.0.0.1"); ok {
+		t.Error("Key not swept after the ban")
+	}
+}
@@ -1,6 +1,7 @@
 package ratelimit
 
 import (
+	"context"
 	"sync"
 	"time"
 )
@@ -12,14 +13,43 @@ func (e RateLimitError) Error() string {
 	return "Rate limit exceeded"
 }
 
-// Rate represents a single client count for a given event.
+// CostError indicates that the events counted at once are over the limit, so they can never be allowed.
+// It's compatible with Yarf's YError, so the RateLimiter returns it to requests costing more than their limit.
+type CostError struct{}
+
+// Implements the error interface returning the ErrorMsg value of each error.
+func (e CostError) Error() string {
+	return "Cost exceeds the rate limit"
+}
+
+// Code returns the error's HTTP code to be used in the response.
+func (e CostError) Code() int {
+	return 400 // Bad Request
+}
+
+// ID returns the error's ID for further reference.
+func (e CostError) ID() int {
+	return 400
+}
+
+// Msg returns the error's message, used to implement the Error interface.
+func (e CostError) Msg() string {
+	return "Cost exceeds the rate limit"
+}
+
+// Body returns the error's content body, if needed, to be returned in the HTTP response.
+func (e CostError) Body() string {
+	return "Bad Request: The request cost exceeds the rate limit."
+}
+
+// Rate represents a single client count for a given event using fixed time windows.
 // Its used internally by RateLimit to count different keys.
 type Rate struct {
 	// Amount of events allowed in a given window
 	Limit int
 
-	// Time window in seconds to limit
-	Window int
+	// Time window to limit
+	Window time.Duration
 
 	// Actual count
 	EventCount int
@@ -27,6 +57,9 @@ type Rate struct {
 	// Start time
 	Start time.Time
 
+	// Time source. Defaults to SystemClock.
+	Clock Clock
+
 	// Sync Mutex
 	sync.RWMutex
 }
@@ -34,125 +67,316 @@ type Rate struct {
 // Count checks for the actual limit/window and resets the window (Start) and Count when corresponding.
 // If the limit has been reached for the actual window it returns a RateLimitError error.
 func (r *Rate) Count() error {
+	return r.CountN(1)
+}
+
+// CountN counts n events at once, like Count.
+// If they don't fit in the actual window it returns a RateLimitError error and none of them is counted.
+func (r *Rate) CountN(n int) error {
+	if n > r.Limit {
+		return CostError{}
+	}
+
 	r.Lock()
 	defer r.Unlock()
 
 	// Reset window
-	if time.Now().After(r.Start.Add(time.Second * time.Duration(r.Window))) {
-		r.Start = time.Now()
+	t := now(r.Clock)
+	if t.After(r.Start.Add(r.Window)) {
+		r.Start = t
 		r.EventCount = 0
 	}
 
-	// Count
-	r.EventCount++
-
 	// Block
-	if r.EventCount >= r.Limit {
+	if r.EventCount+n > r.Limit {
 		return RateLimitError{}
 	}
 
+	// Count
+	r.EventCount += n
+
 	// Continue
 	return nil
 }
 
+// Status returns the limit, the events still allowed in the actual window and the window end.
+func (r *Rate) Status() (limit, remaining int, reset time.Time) {
+	r.RLock()
+	defer r.RUnlock()
+
+	reset = r.Start.Add(r.Window)
+
+	// Window already ended
+	t := now(r.Clock)
+	if t.After(reset) {
+		return r.Limit, r.Limit, t.Add(r.Window)
+	}
+
+	remaining = r.Limit - r.EventCount
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return r.Limit, remaining, reset
+}
+
+// Expired reports if the window ended 2 windows ago at a given time.
+func (r *Rate) Expired(t time.Time) bool {
+	r.RLock()
+	defer r.RUnlock()
+
+	return t.After(r.Start.Add(r.Window * 2))
+}
+
+// Delay returns how long until the actual window ends if the limit has been reached, 0 otherwise.
+func (r *Rate) Delay() time.Duration {
+	return r.DelayN(1)
+}
+
+// DelayN returns how long until the actual window ends if n events don't fit in it, 0 otherwise.
+func (r *Rate) DelayN(n int) time.Duration {
+	r.RLock()
+	defer r.RUnlock()
+
+	t := now(r.Clock)
+	reset := r.Start.Add(r.Window)
+	if t.After(reset) || r.EventCount+n <= r.Limit {
+		return 0
+	}
+
+	return reset.Sub(t)
+}
+
+// RateLimit counts events per key, each key having its own Limiter.
 type RateLimit struct {
 	// Amount of events allowed in a given window
 	Limit int
 
-	// Time window in seconds to limit
-	Window int
+	// Time window to limit
+	Window time.Duration
 
-	// Count storage
-	counter map[string]*Rate
+	// Time source for the limiters and the garbage collector. Defaults to SystemClock.
+	// It should be set before counting.
+	Clock Clock
 
-	// Sync Mutex
-	sync.RWMutex
+	// Creates the Limiter for each key.
+	// Defaults to FixedWindow(Limit, Window) when not set.
+	Algorithm Algorithm
+
+	// Optional counters storage shared between processes.
+	// When set, keys are counted in fixed windows of Limit and Window using a BackendRate, and Algorithm is ignored.
+	Backend Backend
+
+	// What to do when the Backend fails. Defaults to FailOpen.
+	OnFailure FailurePolicy
 
-	// Garbage collection flag
-	gcActive bool
+	// Garbage collector sweep interval.
+	// Defaults to 10 times the window + 1 second when not set.
+	SweepInterval time.Duration
+
+	// Count storage, sharded by key
+	counter shards
+
+	// Garbage collector parent context
+	ctx context.Context
+
+	// Garbage collector
+	gc collector
 }
 
+// New creates a RateLimit allowing limit events per key within a window in seconds.
 func New(limit, window int) *RateLimit {
+	return NewWithContext(context.Background(), limit, window)
+}
+
+// NewWithContext creates a RateLimit whose garbage collector stops when ctx is cancelled.
+// The window is in seconds.
+func NewWithContext(ctx context.Context, limit, window int) *RateLimit {
+	return NewWithWindow(ctx, limit, time.Duration(window)*time.Second)
+}
+
+// NewWithWindow creates a RateLimit allowing limit events per key within any window duration, like 250 milliseconds.
+// The garbage collector stops when ctx is cancelled.
+func NewWithWindow(ctx context.Context, limit int, window time.Duration) *RateLimit {
+	return &RateLimit{
+		Limit:  limit,
+		Window: window,
+		ctx:    ctx,
+	}
+}
+
+// NewWithAlgorithm creates a RateLimit that counts each key using the Limiter created by the given Algorithm.
+// The garbage collector stops when ctx is cancelled.
+func NewWithAlgorithm(ctx context.Context, a Algorithm) *RateLimit {
 	return &RateLimit{
-		Limit:    limit,
-		Window:   window,
-		counter:  make(map[string]*Rate),
-		gcActive: false,
+		Algorithm: a,
+		ctx:       ctx,
 	}
 }
 
-func (rl *RateLimit) Get(key string) *Rate {
-	rl.RLock()
-	defer rl.RUnlock()
+// NewWithBackend creates a RateLimit that stores its counters on a Backend, allowing limit events within a window.
+// The garbage collector, that removes the local state of the keys, stops when ctx is cancelled.
+func NewWithBackend(ctx context.Context, b Backend, limit int, window time.Duration) *RateLimit {
+	return &RateLimit{
+		Limit:   limit,
+		Window:  window,
+		Backend: b,
+		ctx:     ctx,
+	}
+}
 
-	if _, ok := rl.counter[key]; !ok {
-		// Default new Rate
-		return &Rate{
-			Limit:      rl.Limit,
-			Window:     rl.Window,
-			EventCount: 0,
-			Start:      time.Now(),
+// newLimiter creates the Limiter for a new key.
+func (rl *RateLimit) newLimiter(key string) Limiter {
+	if rl.Backend != nil {
+		return &BackendRate{
+			Backend:   rl.Backend,
+			Key:       key,
+			Limit:     rl.Limit,
+			Window:    rl.Window,
+			OnFailure: rl.OnFailure,
+			Clock:     rl.Clock,
 		}
 	}
 
-	return rl.counter[key]
+	if rl.Algorithm != nil {
+		return rl.Algorithm(rl.Clock)
+	}
+
+	return FixedWindow(rl.Limit, rl.Window)(rl.Clock)
+}
+
+// now returns the current time of the RateLimit clock.
+func (rl *RateLimit) now() time.Time {
+	return now(rl.Clock)
+}
+
+// Get returns the Limiter for a given key, or a new empty one if the key isn't being counted.
+func (rl *RateLimit) Get(key string) Limiter {
+	if e, ok := rl.counter.lookup(key); ok {
+		return e.limiter
+	}
+
+	// Default new Limiter
+	return rl.newLimiter(key)
 }
 
 // Count checks on a given key, for the actual limit/window and resets the window (Start) and Count when corresponding.
 // If the limit has been reached for the actual window it returns a RateLimitError error.
 func (rl *RateLimit) Count(key string) error {
-	rl.Lock()
-	defer rl.Unlock()
+	return rl.CountN(key, 1)
+}
+
+// CountN counts n events at once on a given key, like Count, to give some events a higher cost.
+// If they aren't allowed it returns a RateLimitError error.
+func (rl *RateLimit) CountN(key string, n int) error {
+	e := rl.entry(key)
 
-	// Init counter
-	if rl.counter == nil {
-		rl.counter = make(map[string]*Rate)
+	err := e.limiter.CountN(n)
+	e.record(err)
+
+	return err
+}
+
+// ban keeps a banned key from being swept until the ban ends,
+// and records the request blocked by the ban unless it was already counted.
+func (rl *RateLimit) ban(key string, until time.Time, counted bool) {
+	e := rl.entry(key)
+	if !counted {
+		e.blocked.Add(1)
 	}
+	e.hold(until)
+}
 
-	// Init garbage collector
-	if !rl.gcActive {
-		rl.gcActive = true
-		go rl.gc()
-	}
-
-	// Init rate count for the given key
-	if _, ok := rl.counter[key]; !ok {
-		rl.counter[key] = &Rate{
-			Limit:      rl.Limit,
-			Window:     rl.Window,
-			EventCount: 0,
-			Start:      time.Now(),
-		}
+// Reserve counts an event for a key if its limit allows it, and reports if it did.
+// Otherwise, nothing is counted and it returns how long until the next event is allowed.
+// If the event can never be allowed, like with a limit of 0, it returns a CostError instead.
+// It's meant to throttle outgoing events instead of failing, see Wait.
+func (rl *RateLimit) Reserve(key string) (time.Duration, bool, error) {
+	e := rl.entry(key)
+
+	err := e.limiter.Count()
+	e.record(err)
+	if err == nil {
+		return 0, true, nil
+	}
+	if _, ok := err.(RateLimitError); !ok {
+		return 0, false, err
 	}
 
-	return rl.counter[key].Count()
+	return e.limiter.Delay(), false, nil
 }
 
-func (rl *RateLimit) gc() {
-	// 10 times the window + 1
-	t := time.NewTicker(time.Duration((rl.Window+1)*10) * time.Second)
-	defer func(t *time.Ticker, rl *RateLimit) {
-		t.Stop()
-		rl.gcActive = false
-	}(t, rl)
+// Wait blocks until an event for a key is allowed by its limit, and counts it.
+// It returns an error if ctx is cancelled before, or right away if its deadline comes before the event would be allowed.
+// Events that can never be allowed return the CostError right away.
+func (rl *RateLimit) Wait(ctx context.Context, key string) error {
+	for {
+		delay, ok, err := rl.Reserve(key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		// Retry a bit later on a window change race
+		if delay <= 0 {
+			delay = time.Millisecond
+		}
 
-	for _ = range t.C {
-		// Cancel when storage not present
-		if rl.counter == nil {
-			return
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return context.DeadlineExceeded
 		}
 
-		// Check for expired entries.
-		now := time.Now()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
 
-		// Write lock
-		rl.Lock()
-		for key, rate := range rl.counter {
-			// Expired 2 windows ago.
-			if now.After(rate.Start.Add(time.Second * time.Duration(rate.Window*2))) {
-				delete(rl.counter, key)
-			}
+		case <-clockOrSystem(rl.Clock).After(delay):
 		}
-		rl.Unlock()
 	}
 }
+
+// entry returns the entry for a given key, creating it if needed.
+// Limiters have their own lock, and a Backend can be slow, so they're counted without holding the shard lock.
+func (rl *RateLimit) entry(key string) *entry {
+	// Init garbage collector
+	rl.startGC()
+
+	if e, ok := rl.counter.lookup(key); ok {
+		return e
+	}
+
+	return rl.counter.load(key, rl.newLimiter)
+}
+
+// startGC runs the garbage collector if it isn't running yet.
+func (rl *RateLimit) startGC() {
+	// Checked before taking the rl.Sweep method value, that would allocate on every count.
+	if rl.gc.started() {
+		return
+	}
+
+	interval := rl.SweepInterval
+	if interval <= 0 {
+		// 10 times the window + 1 second
+		interval = (rl.Window + time.Second) * 10
+	}
+
+	rl.gc.start(rl.ctx, rl.Clock, interval, rl.Sweep)
+}
+
+// Sweep removes the keys whose Limiter has expired.
+// It's called periodically by the garbage collector and can be called directly.
+// Each shard is locked while it's swept, so counting keys on other shards isn't stopped.
+func (rl *RateLimit) Sweep() {
+	rl.counter.sweep(rl.now())
+}
+
+// Close stops the garbage collector and waits for it to return.
+// The RateLimit keeps counting, but expired keys won't be removed anymore.
+func (rl *RateLimit) Close() error {
+	rl.gc.close()
+
+	return nil
+}
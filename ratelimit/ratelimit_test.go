@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCount(t *testing.T) {
+	rl := New(3, 60)
+	defer rl.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := rl.Count("key"); err != nil {
+			t.Fatalf("Event %d blocked: %s", i, err.Error())
+		}
+	}
+
+	if err := rl.Count("key"); err == nil {
+		t.Error("Limit not reached")
+	}
+
+	// Other keys aren't affected
+	if err := rl.Count("other"); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestSweep(t *testing.T) {
+	rl := New(10, 1)
+	defer rl.Close()
+
+	rl.Count("expired")
+	rl.Count("valid")
+
+	l, _ := rl.counter.lookup("expired")
+	l.limiter.(*Rate).Start = time.Now().Add(-3 * time.Second)
+
+	rl.Sweep()
+
+	if _, ok := rl.counter.lookup("expired"); ok {
+		t.Error("Expired key not removed by Sweep")
+	}
+	if _, ok := rl.counter.lookup("valid"); !ok {
+		t.Error("Valid key removed by Sweep")
+	}
+}
+
+func TestClose(t *testing.T) {
+	rl := New(10, 60)
+	rl.Count("key")
+
+	done := rl.gc.done
+	rl.Close()
+
+	select {
+	case <-done:
+	default:
+		t.Error("Garbage collector still running after Close")
+	}
+
+	// Don't restart after Close
+	rl.Count("key")
+	if rl.gc.done != done {
+		t.Error("Garbage collector restarted after Close")
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rl := NewWithContext(ctx, 10, 60)
+	rl.Count("key")
+
+	cancel()
+
+	select {
+	case <-rl.gc.done:
+	case <-time.After(time.Second):
+		t.Error("Garbage collector still running after context cancellation")
+	}
+}
+
+func TestRateStatus(t *testing.T) {
+	r := FixedWindow(3, time.Minute)(nil)
+	r.Count()
+
+	limit, remaining, reset := r.Status()
+	if limit != 3 || remaining != 2 {
+		t.Errorf("Expected 3/2, got %d/%d", limit, remaining)
+	}
+	if reset.Before(time.Now()) {
+		t.Error("Reset time in the past")
+	}
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"github.com/yarf-framework/yarf"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Headers selects the rate limit headers set on the responses.
+type Headers int
+
+const (
+	// LegacyHeaders are the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (Unix time) headers.
+	LegacyHeaders Headers = 1 << iota
+
+	// DraftHeaders are the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset (seconds)
+	// headers described by the IETF RateLimit header fields draft.
+	DraftHeaders
+)
+
+// Status describes the rate limit state of a request, used to set the headers and render blocked responses.
+type Status struct {
+	// Counted key
+	Key string
+
+	// Events allowed
+	Limit int
+
+	// Events still allowed
+	Remaining int
+
+	// Time when the full limit is available again
+	Reset time.Time
+
+	// Time to wait before retrying, for blocked requests
+	RetryAfter time.Duration
+}
+
+// Renderer returns the content type and body of the blocked responses.
+type Renderer func(c *yarf.Context, s Status) (contentType, body string)
+
+// PlainText renders the blocked responses as plain text. It's the default Renderer.
+func PlainText(c *yarf.Context, s Status) (string, string) {
+	return "text/plain; charset=utf-8", "Too Many Requests: Try again later."
+}
+
+// ProblemJSON renders the blocked responses as RFC 7807 problem details.
+func ProblemJSON(c *yarf.Context, s Status) (string, string) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"type":     "about:blank",
+		"title":    "Too Many Requests",
+		"status":   429,
+		"detail":   "Rate limit exceeded, try again in " + strconv.Itoa(seconds(s.RetryAfter)) + " seconds.",
+		"instance": c.Request.URL.Path,
+	})
+
+	return "application/problem+json", string(body)
+}
+
+// setHeaders sets the rate limit headers on the response, relative to the given time.
+func setHeaders(c *yarf.Context, h Headers, s Status, now time.Time, blocked bool) {
+	// Default to both
+	if h == 0 {
+		h = LegacyHeaders | DraftHeaders
+	}
+
+	header := c.Response.Header()
+
+	if h&LegacyHeaders != 0 {
+		header.Set("X-RateLimit-Limit", strconv.Itoa(s.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(s.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.Itoa(int(s.Reset.Unix())))
+	}
+
+	if h&DraftHeaders != 0 {
+		header.Set("RateLimit-Limit", strconv.Itoa(s.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(s.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(seconds(s.Reset.Sub(now))))
+	}
+
+	if blocked {
+		header.Set("Retry-After", strconv.Itoa(seconds(s.RetryAfter)))
+	}
+}
+
+// seconds rounds up a duration to seconds, at least 0.
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBlockedResponse(t *testing.T) {
+	m := YarfMiddleware(1, 60)
+	defer m.Close()
+	m.Renderer = ProblemJSON
+
+	m.PreDispatch(newTestContext("GET", "/", "10.0.0.1"))
+
+	c := newTestContext("GET", "/items", "10.0.0.1")
+	err := m.PreDispatch(c)
+	if err == nil {
+		t.Fatal("Limit exceeded")
+	}
+
+	ye, ok := err.(*YarfError)
+	if !ok {
+		t.Fatal("Blocked request didn't return a YarfError")
+	}
+	if ye.Error() != "Too Many Requests" {
+		t.Errorf("Unexpected error message: %s", ye.Error())
+	}
+
+	h := c.Response.Header()
+	for _, name := range []string{"Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"} {
+		if h.Get(name) == "" {
+			t.Errorf("Missing %s header", name)
+		}
+	}
+	if h.Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Expected 0 remaining, got %s", h.Get("RateLimit-Remaining"))
+	}
+	if h.Get("Content-Type") != "application/problem+json" {
+		t.Errorf("Unexpected content type %s", h.Get("Content-Type"))
+	}
+
+	var problem map[string]interface{}
+	if err := json.Unmarshal([]byte(ye.Body()), &problem); err != nil {
+		t.Fatal(err.Error())
+	}
+	if problem["status"] != float64(429) || problem["instance"] != "/items" {
+		t.Errorf("Unexpected problem details: %s", ye.Body())
+	}
+}
+
+func TestHeadersSelection(t *testing.T) {
+	m := YarfMiddleware(5, 60)
+	defer m.Close()
+	m.Headers = DraftHeaders
+
+	c := newTestContext("GET", "/", "10.0.0.1")
+	m.PreDispatch(c)
+
+	if c.Response.Header().Get("X-RateLimit-Limit") != "" {
+		t.Error("Legacy headers set")
+	}
+	if c.Response.Header().Get("RateLimit-Remaining") != "4" {
+		t.Errorf("Expected 4 remaining, got %s", c.Response.Header().Get("RateLimit-Remaining"))
+	}
+	if c.Response.Header().Get("Retry-After") != "" {
+		t.Error("Retry-After set on allowed request")
+	}
+}
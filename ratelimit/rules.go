@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"github.com/yarf-framework/yarf"
+	"path"
+	"strings"
+)
+
+// Rule applies its own RateLimit and KeyFunc to the requests it matches.
+// Empty matching fields match any request.
+type Rule struct {
+	// Name used for reference.
+	Name string
+
+	// HTTP method, like "POST".
+	Method string
+
+	// Path pattern. It can be an exact path like "/login", a prefix ending in "*" like "/api/*",
+	// or a path.Match pattern like "/users/*/avatar".
+	Path string
+
+	// Request host, like "api.example.com".
+	Host string
+
+	// Client class, as returned by the RateLimiter's Classifier, like "premium".
+	Class string
+
+	// Rate limit for the matching requests.
+	Limit *RateLimit
+
+	// Extracts the key to count for each request. Defaults to ClientNet64.
+	Key KeyFunc
+
+	// Computes the cost of each request. Defaults to the RateLimiter's Cost.
+	Cost CostFunc
+
+	// Additional limits counted on the matching requests, like a per /48 limit over a per /64 Key.
+	Layers []*Layer
+
+	// Requests allowed and blocked by the rule
+	tally tally
+}
+
+// Layer is an additional limit with its own key, counted on the same requests as a Rule or the default limit of a RateLimiter.
+// Layers build hierarchical limits: the requests are blocked when any of the limits is exceeded.
+type Layer struct {
+	// Name used for reference.
+	Name string
+
+	// Rate limit of the layer.
+	Limit *RateLimit
+
+	// Extracts the key to count for each request. Defaults to ClientNet64.
+	Key KeyFunc
+}
+
+// Classifier returns the class of the client making a request, used to match the Rule's Class.
+type Classifier func(c *yarf.Context) string
+
+// Match reports if the rule applies to a request of the given class.
+func (r *Rule) Match(c *yarf.Context, class string) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, c.Request.Method) {
+		return false
+	}
+	if r.Host != "" && !strings.EqualFold(r.Host, hostname(c.Request.Host)) {
+		return false
+	}
+	if r.Class != "" && r.Class != class {
+		return false
+	}
+
+	return r.matchPath(c.Request.URL.Path)
+}
+
+// matchPath checks the request path against the rule's Path pattern.
+func (r *Rule) matchPath(p string) bool {
+	return r.Path == "" || matchPath(r.Path, p)
+}
+
+// matchPath checks a path against a pattern: an exact path, a prefix ending in "*" or a path.Match pattern.
+func matchPath(pattern, p string) bool {
+	switch {
+	case strings.HasSuffix(pattern, "*") && !strings.ContainsAny(pattern[:len(pattern)-1], "*?["):
+		return strings.HasPrefix(p, pattern[:len(pattern)-1])
+
+	case strings.ContainsAny(pattern, "*?["):
+		ok, _ := path.Match(pattern, p)
+		return ok
+	}
+
+	return pattern == p
+}
+
+// Specificity scores the rule so the most specific matching rule wins.
+// The path weights the most: exact paths over patterns, and longer patterns over shorter ones.
+// Then host, class and method, in that order.
+func (r *Rule) Specificity() int {
+	score := 0
+
+	if r.Path != "" {
+		if strings.ContainsAny(r.Path, "*?[") {
+			score = len(strings.Trim(r.Path, "*?["))
+		} else {
+			score = 1024 + len(r.Path)
+		}
+	}
+
+	score *= 8
+	if r.Host != "" {
+		score += 4
+	}
+	if r.Class != "" {
+		score += 2
+	}
+	if r.Method != "" {
+		score++
+	}
+
+	return score
+}
+
+// hostname removes the port from a request host.
+func hostname(host string) string {
+	if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+		return host[:i]
+	}
+
+	return host
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"github.com/yarf-framework/yarf"
+	"testing"
+)
+
+func TestRuleMatch(t *testing.T) {
+	login := &Rule{Name: "login", Method: "POST", Path: "/login", Limit: New(5, 60)}
+	api := &Rule{Name: "api", Method: "GET", Path: "/api/*", Limit: New(1000, 60)}
+	premium := &Rule{Name: "premium", Path: "/api/*", Class: "premium", Limit: New(10000, 60)}
+	avatar := &Rule{Name: "avatar", Path: "/users/*/avatar", Limit: New(10, 60)}
+
+	m := NewRuleLimiter(login, api, premium, avatar)
+	defer m.Close()
+	m.Classifier = func(c *yarf.Context) string {
+		return c.Request.Header.Get("X-Class")
+	}
+
+	tests := []struct {
+		method, path, class string
+		rule                *Rule
+	}{
+		{"POST", "/login", "", login},
+		{"GET", "/login", "", nil},
+		{"GET", "/api/items", "", api},
+		{"GET", "/api/items", "premium", premium},
+		{"POST", "/api/items", "", nil},
+		{"POST", "/api/items", "premium", premium},
+		{"GET", "/users/1/avatar", "", avatar},
+		{"GET", "/other", "", nil},
+	}
+
+	for _, test := range tests {
+		c := newTestContext(test.method, test.path, "10.0.0.1")
+		c.Request.Header.Set("X-Class", test.class)
+
+		if r := m.Match(c); r != test.rule {
+			t.Errorf("%s %s (%s): unexpected rule %v", test.method, test.path, test.class, r)
+		}
+	}
+}
+
+func TestRuleLimiter(t *testing.T) {
+	m := NewRuleLimiter(&Rule{Method: "POST", Path: "/login", Limit: New(1, 60)})
+	defer m.Close()
+
+	if err := m.PreDispatch(newTestContext("POST", "/login", "10.0.0.1")); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := m.PreDispatch(newTestContext("POST", "/login", "10.0.0.1")); err == nil {
+		t.Error("Rule limit exceeded")
+	}
+
+	// Not matching, not limited
+	for i := 0; i < 3; i++ {
+		if err := m.PreDispatch(newTestContext("GET", "/", "10.0.0.1")); err != nil {
+			t.Error("Request without matching rule limited")
+		}
+	}
+}
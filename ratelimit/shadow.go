@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"github.com/yarf-framework/yarf"
+	"log"
+	"strconv"
+)
+
+// Reasons to block a request, as reported by ShadowEvent.
+const (
+	// ReasonLimit is a request exceeding its rate limit.
+	ReasonLimit = "limit"
+
+	// ReasonBan is a request from a key banned by the Penalty.
+	ReasonBan = "ban"
+
+	// ReasonDeny is a request from an IP range in the Deny list.
+	ReasonDeny = "deny"
+)
+
+// ShadowEvent describes a request that a RateLimiter in shadow mode would have blocked.
+type ShadowEvent struct {
+	// Rate limit state of the request. For denied requests, only the Key is set to the client IP.
+	Status
+
+	// Why the request would have been blocked: ReasonLimit, ReasonBan or ReasonDeny.
+	Reason string
+
+	// Name of the matching rule, empty for the default limit.
+	Rule string
+
+	// Request method
+	Method string
+
+	// Request path
+	Path string
+}
+
+// ShadowFunc receives the requests that a RateLimiter in shadow mode would have blocked.
+type ShadowFunc func(c *yarf.Context, e ShadowEvent)
+
+// LogShadow is the default ShadowFunc, it logs a line for each request that would have been blocked.
+func LogShadow(c *yarf.Context, e ShadowEvent) {
+	log.Printf(
+		"SHADOW: rate limit %s | key=%s | rule=%s | %s %s | limit=%d | remaining=%d | retry=%ss",
+		e.Reason, e.Key, e.Rule, e.Method, e.Path, e.Limit, e.Remaining, strconv.Itoa(seconds(e.RetryAfter)),
+	)
+}
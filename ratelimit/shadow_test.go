@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"github.com/yarf-framework/yarf"
+	"testing"
+)
+
+func TestShadowMode(t *testing.T) {
+	var events []ShadowEvent
+
+	m := YarfMiddleware(1, 60)
+	defer m.Close()
+	m.Shadow = true
+	m.Deny, _ = NewCIDRList("192.0.2.0/24")
+	m.OnShadow = func(c *yarf.Context, e ShadowEvent) {
+		events = append(events, e)
+	}
+
+	for i := 0; i < 3; i++ {
+		c := newTestContext("GET", "/items", "10.0.0.1")
+		if err := m.PreDispatch(c); err != nil {
+			t.Fatalf("Shadow mode blocked a request: %s", err.Error())
+		}
+		if h := c.Response.Header().Get("RateLimit-Limit"); h != "" {
+			t.Error("Shadow mode set rate limit headers")
+		}
+	}
+	if err := m.PreDispatch(newTestContext("GET", "/", "192.0.2.1")); err != nil {
+		t.Fatalf("Shadow mode denied a request: %s", err.Error())
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 reports, got %d", len(events))
+	}
+	if e := events[0]; e.Reason != ReasonLimit || e.Key != "10.0.0.1" || e.Path != "/items" || e.Limit != 1 || e.Remaining != 0 {
+		t.Errorf("Unexpected report %+v", e)
+	}
+	if e := events[2]; e.Reason != ReasonDeny || e.Key != "192.0.2.1" {
+		t.Errorf("Unexpected deny report %+v", e)
+	}
+}
+
+func TestShadowCandidate(t *testing.T) {
+	var events []ShadowEvent
+
+	m := YarfMiddleware(3, 60)
+	defer m.Close()
+	m.Candidate = NewRuleLimiter(&Rule{Name: "strict", Limit: New(1, 60)})
+	m.Candidate.OnShadow = func(c *yarf.Context, e ShadowEvent) {
+		events = append(events, e)
+	}
+
+	for i := 0; i < 3; i++ {
+		c := newTestContext("GET", "/", "10.0.0.1")
+		if err := m.PreDispatch(c); err != nil {
+			t.Fatalf("Request within the enforced limit blocked: %s", err.Error())
+		}
+		if h := c.Response.Header().Get("RateLimit-Limit"); h != "3" {
+			t.Errorf("Expected the enforced limit on headers, got %s", h)
+		}
+	}
+	if err := m.PreDispatch(newTestContext("GET", "/", "10.0.0.1")); err == nil {
+		t.Error("Enforced limit exceeded")
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 candidate reports, got %d", len(events))
+	}
+	if events[0].Rule != "strict" {
+		t.Errorf("Unexpected rule %s", events[0].Rule)
+	}
+}
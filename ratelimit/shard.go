@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shardCount is the number of partitions of the RateLimit counters.
+// It's a power of 2 so the shard of a key is taken from its hash bits.
+const shardCount = 64
+
+// entry is a counted key with its Limiter and how many times it was allowed and blocked.
+type entry struct {
+	// Limiter of the key
+	limiter Limiter
+
+	// Allowed and blocked counts
+	allowed, blocked atomic.Uint64
+
+	// End of the ban of the key, in Unix nanoseconds. The entry isn't swept before it.
+	until atomic.Int64
+}
+
+// record adds the result of a count to the entry.
+func (e *entry) record(err error) {
+	if err == nil {
+		e.allowed.Add(1)
+	} else if _, ok := err.(RateLimitError); ok {
+		e.blocked.Add(1)
+	}
+}
+
+// hold keeps the entry from being swept until a ban ends.
+func (e *entry) hold(until time.Time) {
+	u := until.UnixNano()
+	for {
+		old := e.until.Load()
+		if u <= old || e.until.CompareAndSwap(old, u) {
+			return
+		}
+	}
+}
+
+// expired reports if the entry can be removed at a given time.
+func (e *entry) expired(t time.Time) bool {
+	return e.limiter.Expired(t) && t.UnixNano() >= e.until.Load()
+}
+
+// shard is a partition of the RateLimit counters with its own lock,
+// so requests for keys on different shards don't wait for each other.
+type shard struct {
+	// Count storage
+	counter map[string]*entry
+
+	// Sync Mutex
+	sync.RWMutex
+}
+
+// shards is the sharded counter store of a RateLimit.
+type shards [shardCount]shard
+
+// get returns the shard for a given key.
+func (s *shards) get(key string) *shard {
+	return &s[fnv32(key)&(shardCount-1)]
+}
+
+// lookup returns the entry of a key, if it's being counted.
+func (s *shards) lookup(key string) (*entry, bool) {
+	sh := s.get(key)
+
+	sh.RLock()
+	defer sh.RUnlock()
+
+	e, ok := sh.counter[key]
+
+	return e, ok
+}
+
+// load returns the entry of a key, creating it with newLimiter if it isn't being counted.
+// Callers should try lookup first, as most requests are for keys already counted, which only need the read lock.
+func (s *shards) load(key string, newLimiter func(string) Limiter) *entry {
+	sh := s.get(key)
+
+	sh.Lock()
+	defer sh.Unlock()
+
+	if sh.counter == nil {
+		sh.counter = make(map[string]*entry)
+	}
+
+	e, ok := sh.counter[key]
+	if !ok {
+		e = &entry{limiter: newLimiter(key)}
+		sh.counter[key] = e
+	}
+
+	return e
+}
+
+// sweep removes the entries expired at a given time, locking one shard at a time.
+func (s *shards) sweep(t time.Time) {
+	for i := range s {
+		sh := &s[i]
+
+		sh.Lock()
+		for key, e := range sh.counter {
+			if e.expired(t) {
+				delete(sh.counter, key)
+			}
+		}
+		sh.Unlock()
+	}
+}
+
+// each calls f for every key being counted, locking one shard at a time for reading.
+// f must not count keys of the same RateLimit.
+func (s *shards) each(f func(key string, e *entry)) {
+	for i := range s {
+		sh := &s[i]
+
+		sh.RLock()
+		for key, e := range sh.counter {
+			f(key, e)
+		}
+		sh.RUnlock()
+	}
+}
+
+// fnv32 returns the FNV-1a hash of a key, without allocating.
+func fnv32(key string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+
+	return h
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardsConcurrentCount(t *testing.T) {
+	rl := New(1000, 60)
+	defer rl.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				rl.Count("key" + strconv.Itoa(j))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for j := 0; j < 100; j++ {
+		if _, remaining, _ := rl.Get("key" + strconv.Itoa(j)).Status(); remaining != 992 {
+			t.Fatalf("Expected 992 remaining, got %d", remaining)
+		}
+	}
+}
+
+func TestShardsDistribution(t *testing.T) {
+	var s shards
+	for i := 0; i < 10000; i++ {
+		s.load("10.0."+strconv.Itoa(i/256)+"."+strconv.Itoa(i%256), func(string) Limiter {
+			return NewBucket(1, 1)
+		})
+	}
+
+	for i := range s {
+		if n := len(s[i].counter); n < 10000/shardCount/2 {
+			t.Errorf("Shard %d has only %d keys", i, n)
+		}
+	}
+
+	s.sweep(time.Now().Add(time.Hour))
+	for i := range s {
+		if n := len(s[i].counter); n != 0 {
+			t.Errorf("Shard %d has %d keys after sweep", i, n)
+		}
+	}
+}
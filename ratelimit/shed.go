@@ -0,0 +1,235 @@
+package ratelimit
+
+import (
+	"github.com/yarf-framework/yarf"
+	"sync"
+	"time"
+)
+
+// ShedError is returned by the LoadShedder for the requests dropped to protect the service.
+type ShedError struct{}
+
+// Implements the error interface returning the ErrorMsg value of each error.
+func (e *ShedError) Error() string {
+	return "Service Unavailable"
+}
+
+// Code returns the error's HTTP code to be used in the response.
+func (e *ShedError) Code() int {
+	return 503
+}
+
+// ID returns the error's ID for further reference.
+func (e *ShedError) ID() int {
+	return 503
+}
+
+// Msg returns the error's message, used to implement the Error interface.
+func (e *ShedError) Msg() string {
+	return "Service Unavailable"
+}
+
+// Body returns the error's content body, if needed, to be returned in the HTTP response.
+func (e *ShedError) Body() string {
+	return "Service Unavailable: Server overloaded, try again later."
+}
+
+// Priority of a request for the LoadShedder. Lower priorities are shed first.
+type Priority int
+
+const (
+	// PriorityLow requests, like crawlers and batch jobs, get up to half of the concurrency limit.
+	PriorityLow Priority = iota
+
+	// PriorityNormal requests get up to 80% of the concurrency limit. It's the default priority.
+	PriorityNormal
+
+	// PriorityHigh requests, like authenticated users, get the full concurrency limit.
+	PriorityHigh
+
+	// PriorityCritical requests, like health checks, are never shed.
+	PriorityCritical
+)
+
+// share returns the part of the concurrency limit available to a priority.
+func (p Priority) share() float64 {
+	switch {
+	case p <= PriorityLow:
+		return 0.5
+
+	case p == PriorityNormal:
+		return 0.8
+	}
+
+	return 1
+}
+
+// PriorityFunc returns the priority of a request.
+type PriorityFunc func(c *yarf.Context) Priority
+
+// LoadShedder middleware adapts the amount of requests in progress to the measured latency,
+// and sheds the lowest priority requests first with a 503 status when it's reached.
+//
+// The concurrency limit follows an AIMD (additive increase, multiplicative decrease) algorithm:
+// it grows slowly while requests complete within the Target latency, and it's cut by Backoff when they don't.
+type LoadShedder struct {
+	yarf.Middleware
+
+	// Latency the requests should complete within. Defaults to 1 second.
+	Target time.Duration
+
+	// Concurrency limit bounds. The limit starts at Max.
+	// Min defaults to 1, and Max to 100, or to Min when it's lower.
+	Min, Max int
+
+	// Factor applied to the limit when the latency exceeds the Target, between 0 and 1. Defaults to 0.9.
+	Backoff float64
+
+	// Classifies the requests. Defaults to PriorityNormal for all of them.
+	Priority PriorityFunc
+
+	// Time source for the latencies. Defaults to SystemClock.
+	Clock Clock
+
+	// Actual concurrency limit
+	limit float64
+
+	// Requests in progress
+	inflight int
+
+	// Last limit decrease, so it's cut once per Target instead of once per slow request
+	decreased time.Time
+
+	// Start time of the requests in progress
+	started map[*yarf.Context]time.Time
+
+	// Sync Mutex
+	sync.Mutex
+}
+
+// NewLoadShedder creates the middleware keeping the requests latency under target,
+// with a concurrency limit between min and max.
+func NewLoadShedder(target time.Duration, min, max int) *LoadShedder {
+	return &LoadShedder{
+		Target: target,
+		Min:    min,
+		Max:    max,
+	}
+}
+
+// bounds returns the concurrency limit bounds, with their defaults.
+func (m *LoadShedder) bounds() (min, max float64) {
+	min, max = float64(m.Min), float64(m.Max)
+	if min < 1 {
+		min = 1
+	}
+	if max <= 0 {
+		max = 100
+	}
+	if max < min {
+		max = min
+	}
+
+	return min, max
+}
+
+// target returns the Target latency, with its default.
+func (m *LoadShedder) target() time.Duration {
+	if m.Target <= 0 {
+		return time.Second
+	}
+
+	return m.Target
+}
+
+// PreDispatch admits the request if the concurrency limit for its priority allows it.
+func (m *LoadShedder) PreDispatch(c *yarf.Context) error {
+	p := PriorityNormal
+	if m.Priority != nil {
+		p = m.Priority(c)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if m.started == nil {
+		m.started = make(map[*yarf.Context]time.Time)
+		_, m.limit = m.bounds()
+	}
+
+	if p < PriorityCritical && float64(m.inflight) >= m.limit*p.share() {
+		c.Response.Header().Set("Retry-After", "1")
+
+		return &ShedError{}
+	}
+
+	m.inflight++
+	m.started[c] = now(m.Clock)
+
+	return nil
+}
+
+// release ends the request, if it was admitted, and adjusts the limit to its latency.
+func (m *LoadShedder) release(c *yarf.Context) {
+	m.Lock()
+	defer m.Unlock()
+
+	start, ok := m.started[c]
+	if !ok {
+		return
+	}
+	delete(m.started, c)
+	m.inflight--
+
+	t := now(m.Clock)
+	target := m.target()
+	if t.Sub(start) <= target {
+		// Additive increase: about 1 per limit requests completed in time
+		m.limit += 1 / m.limit
+	} else if t.Sub(m.decreased) >= target {
+		// Multiplicative decrease
+		backoff := m.Backoff
+		if backoff <= 0 || backoff >= 1 {
+			backoff = 0.9
+		}
+
+		m.limit *= backoff
+		m.decreased = t
+	}
+
+	min, max := m.bounds()
+	if m.limit < min {
+		m.limit = min
+	}
+	if m.limit > max {
+		m.limit = max
+	}
+}
+
+// PostDispatch ends the request.
+func (m *LoadShedder) PostDispatch(c *yarf.Context) error {
+	m.release(c)
+
+	return nil
+}
+
+// End ends the request if PostDispatch didn't, like after an error.
+func (m *LoadShedder) End(c *yarf.Context) error {
+	m.release(c)
+
+	return nil
+}
+
+// Limit returns the actual concurrency limit and the amount of requests in progress.
+func (m *LoadShedder) Limit() (limit, inflight int) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.started == nil {
+		_, max := m.bounds()
+
+		return int(max), 0
+	}
+
+	return int(m.limit), m.inflight
+}
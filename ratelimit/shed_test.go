@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"github.com/yarf-framework/yarf"
+	"testing"
+	"time"
+)
+
+func TestLoadShedderPriorities(t *testing.T) {
+	m := NewLoadShedder(100*time.Millisecond, 1, 10)
+	m.Priority = func(c *yarf.Context) Priority {
+		switch c.Request.URL.Path {
+		case "/health":
+			return PriorityCritical
+		case "/crawl":
+			return PriorityLow
+		case "/account":
+			return PriorityHigh
+		}
+
+		return PriorityNormal
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := m.PreDispatch(newTestContext("GET", "/crawl", "10.0.0.1")); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if err := m.PreDispatch(newTestContext("GET", "/crawl", "10.0.0.1")); err == nil {
+		t.Error("Low priority share exceeded")
+	} else if err.(*ShedError).Code() != 503 {
+		t.Error("Unexpected status code")
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := m.PreDispatch(newTestContext("GET", "/", "10.0.0.1")); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if err := m.PreDispatch(newTestContext("GET", "/", "10.0.0.1")); err == nil {
+		t.Error("Normal priority share exceeded")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := m.PreDispatch(newTestContext("GET", "/account", "10.0.0.1")); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if err := m.PreDispatch(newTestContext("GET", "/account", "10.0.0.1")); err == nil {
+		t.Error("Limit exceeded")
+	}
+
+	if err := m.PreDispatch(newTestContext("GET", "/health", "10.0.0.1")); err != nil {
+		t.Error("Critical request shed")
+	}
+}
+
+func TestLoadShedderAIMD(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	m := NewLoadShedder(100*time.Millisecond, 2, 10)
+	m.Backoff = 0.5
+	m.Clock = clock
+
+	request := func(latency time.Duration) {
+		c := newTestContext("GET", "/", "10.0.0.1")
+		if err := m.PreDispatch(c); err != nil {
+			t.Fatal(err.Error())
+		}
+		clock.Advance(latency)
+		m.PostDispatch(c)
+		m.End(c)
+	}
+
+	// Slow requests cut the limit, once per target
+	request(time.Second)
+	if limit, _ := m.Limit(); limit != 5 {
+		t.Errorf("Expected limit 5, got %d", limit)
+	}
+	request(time.Second)
+	request(time.Second)
+	if limit, inflight := m.Limit(); limit != 2 || inflight != 0 {
+		t.Errorf("Expected limit 2 and 0 in flight, got %d and %d", limit, inflight)
+	}
+
+	// Fast requests grow it back slowly
+	for i := 0; i < 10; i++ {
+		request(time.Millisecond)
+	}
+	if limit, _ := m.Limit(); limit <= 2 || limit >= 10 {
+		t.Errorf("Unexpected limit %d", limit)
+	}
+}
+
+func TestLoadShedderDefaults(t *testing.T) {
+	m := &LoadShedder{Target: 100 * time.Millisecond}
+	if limit, _ := m.Limit(); limit != 100 {
+		t.Errorf("Expected default limit 100, got %d", limit)
+	}
+	if err := m.PreDispatch(newTestContext("GET", "/", "10.0.0.1")); err != nil {
+		t.Error("Request shed without Max")
+	}
+
+	// Requests within the default Target keep the limit.
+	clock := NewFakeClock(time.Unix(1000, 0))
+	m = &LoadShedder{Max: 10, Clock: clock}
+	for i := 0; i < 5; i++ {
+		c := newTestContext("GET", "/", "10.0.0.1")
+		m.PreDispatch(c)
+		clock.Advance(100 * time.Millisecond)
+		m.PostDispatch(c)
+	}
+	if limit, _ := m.Limit(); limit != 10 {
+		t.Errorf("Expected limit 10 without Target, got %d", limit)
+	}
+
+	// Max below Min
+	m = NewLoadShedder(100*time.Millisecond, 4, 2)
+	if limit, _ := m.Limit(); limit != 4 {
+		t.Errorf("Expected limit 4, got %d", limit)
+	}
+}
@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingRate implements the weighted sliding window counter algorithm for a single key.
+// The count of the previous window is weighted by how much of it still overlaps the sliding window,
+// which smooths the enforcement and avoids the burst allowed by Rate around a window boundary.
+type SlidingRate struct {
+	// Amount of events allowed in a given window
+	Limit int
+
+	// Time window to limit
+	Window time.Duration
+
+	// Events counted in the previous window
+	PrevCount int
+
+	// Events counted in the actual window
+	EventCount int
+
+	// Actual window start time
+	Start time.Time
+
+	// Time source. Defaults to SystemClock.
+	Clock Clock
+
+	// Sync Mutex
+	sync.RWMutex
+}
+
+// NewSlidingRate creates an empty SlidingRate.
+func NewSlidingRate(limit int, window time.Duration) *SlidingRate {
+	return &SlidingRate{
+		Limit:  limit,
+		Window: window,
+		Start:  time.Now(),
+	}
+}
+
+// SlidingWindow returns the Algorithm that allows limit events within any window of the given duration.
+func SlidingWindow(limit int, window time.Duration) Algorithm {
+	return func(clock Clock) Limiter {
+		r := NewSlidingRate(limit, window)
+		r.Clock = clock
+		r.Start = now(clock)
+
+		return r
+	}
+}
+
+// advance moves the windows up to the given time.
+// It must be called holding the write lock.
+func (r *SlidingRate) advance(now time.Time) {
+	w := r.Window
+	if w <= 0 || now.Before(r.Start.Add(w)) {
+		return
+	}
+
+	// Next window: the actual one becomes the previous
+	if now.Before(r.Start.Add(2 * w)) {
+		r.PrevCount = r.EventCount
+	} else {
+		r.PrevCount = 0
+	}
+
+	r.EventCount = 0
+	r.Start = r.Start.Add(now.Sub(r.Start) / w * w)
+}
+
+// estimate returns the weighted count for the sliding window ending at the given time.
+func (r *SlidingRate) estimate(now time.Time) float64 {
+	w := r.Window
+	if w <= 0 {
+		return float64(r.EventCount)
+	}
+
+	overlap := 1 - float64(now.Sub(r.Start))/float64(w)
+
+	return float64(r.PrevCount)*overlap + float64(r.EventCount)
+}
+
+// Count registers an event if the sliding window count allows it.
+// Otherwise it returns a RateLimitError error and the event isn't counted.
+func (r *SlidingRate) Count() error {
+	return r.CountN(1)
+}
+
+// CountN registers n events at once if the sliding window count allows them, like Count.
+func (r *SlidingRate) CountN(n int) error {
+	if n > r.Limit {
+		return CostError{}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	t := now(r.Clock)
+	r.advance(t)
+
+	// Block
+	if r.estimate(t)+float64(n) > float64(r.Limit) {
+		return RateLimitError{}
+	}
+
+	// Count
+	r.EventCount += n
+
+	// Continue
+	return nil
+}
+
+// Status returns the limit, the events still allowed in the sliding window
+// and the time when all counted events are out of it.
+func (r *SlidingRate) Status() (limit, remaining int, reset time.Time) {
+	r.Lock()
+	defer r.Unlock()
+
+	t := now(r.Clock)
+	r.advance(t)
+
+	remaining = int(float64(r.Limit) - r.estimate(t))
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return r.Limit, remaining, r.empty(t)
+}
+
+// Delay returns how long until the weighted count allows a new event, 0 if it's allowed now.
+func (r *SlidingRate) Delay() time.Duration {
+	return r.DelayN(1)
+}
+
+// DelayN returns how long until the weighted count allows n events at once, 0 if they're allowed now.
+func (r *SlidingRate) DelayN(n int) time.Duration {
+	r.Lock()
+	defer r.Unlock()
+
+	t := now(r.Clock)
+	r.advance(t)
+
+	if r.Window <= 0 || r.estimate(t)+float64(n) <= float64(r.Limit) {
+		return 0
+	}
+
+	// Allowed in this window when enough of the previous count slides out.
+	free := float64(r.Limit - n - r.EventCount)
+	if free >= 0 && r.PrevCount > 0 {
+		x := float64(r.Window) * (1 - free/float64(r.PrevCount))
+
+		return r.Start.Add(time.Duration(x)).Sub(t)
+	}
+
+	// Next window, when enough of the actual count slides out.
+	x := 0.0
+	if r.EventCount > 0 {
+		x = float64(r.Window) * (1 - float64(r.Limit-n)/float64(r.EventCount))
+	}
+	if x < 0 {
+		x = 0
+	}
+
+	return r.Start.Add(r.Window + time.Duration(x)).Sub(t)
+}
+
+// Expired reports if no counted event is inside the sliding window at a given time.
+func (r *SlidingRate) Expired(t time.Time) bool {
+	r.RLock()
+	defer r.RUnlock()
+
+	return !t.Before(r.empty(t))
+}
+
+// empty returns the time when all counted events are out of the sliding window.
+func (r *SlidingRate) empty(now time.Time) time.Time {
+	switch {
+	case r.EventCount > 0:
+		return r.Start.Add(2 * r.Window)
+
+	case r.PrevCount > 0:
+		return r.Start.Add(r.Window)
+	}
+
+	return now
+}
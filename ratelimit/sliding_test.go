@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/yarf-framework/yarf"
+	"testing"
+	"time"
+)
+
+func TestSlidingRate(t *testing.T) {
+	r := NewSlidingRate(4, 10*time.Second)
+
+	for i := 0; i < 4; i++ {
+		if err := r.Count(); err != nil {
+			t.Fatalf("Event %d blocked: %s", i, err.Error())
+		}
+	}
+	if err := r.Count(); err == nil {
+		t.Error("Limit exceeded")
+	}
+
+	// Half way through the next window, half of the previous count is still inside the sliding window.
+	r.Start = r.Start.Add(-15 * time.Second)
+
+	_, remaining, _ := r.Status()
+	if remaining != 2 {
+		t.Errorf("Expected 2 remaining, got %d", remaining)
+	}
+
+	r.Count()
+	r.Count()
+	if err := r.Count(); err == nil {
+		t.Error("Sliding window allowed a boundary burst")
+	}
+}
+
+func TestSlidingRateExpired(t *testing.T) {
+	r := NewSlidingRate(4, 10*time.Second)
+	if !r.Expired(time.Now()) {
+		t.Error("Empty rate not expired")
+	}
+
+	r.Count()
+	if r.Expired(time.Now()) {
+		t.Error("Used rate expired")
+	}
+	if !r.Expired(time.Now().Add(20 * time.Second)) {
+		t.Error("Rate expired after 2 windows not expired")
+	}
+}
+
+func TestSlidingWindowMiddleware(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	rl := NewWithAlgorithm(context.Background(), SlidingWindow(4, 10*time.Second))
+	rl.Clock = clock
+	m := NewRateLimiter(rl)
+	defer m.Close()
+
+	request := func() (*yarf.Context, error) {
+		c := newTestContext("GET", "/", "10.0.0.1")
+		err := m.PreDispatch(c)
+
+		return c, err
+	}
+	expect := func(c *yarf.Context, remaining, reset string) {
+		t.Helper()
+
+		h := c.Response.Header()
+		if h.Get("X-RateLimit-Remaining") != remaining || h.Get("X-RateLimit-Reset") != reset {
+			t.Errorf("Expected %s remaining until %s, got %s until %s", remaining, reset, h.Get("X-RateLimit-Remaining"), h.Get("X-RateLimit-Reset"))
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := request(); err != nil {
+			t.Fatalf("Request %d blocked: %s", i, err.Error())
+		}
+	}
+	c, err := request()
+	if err == nil {
+		t.Fatal("Limit exceeded")
+	}
+	expect(c, "0", "1020")
+
+	// Half way through the next window, half of the previous count is still inside the sliding window.
+	clock.Advance(15 * time.Second)
+
+	c, err = request()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expect(c, "1", "1030")
+
+	request()
+	if c, err = request(); err == nil {
+		t.Error("Sliding window allowed a boundary burst")
+	}
+	expect(c, "0", "1030")
+
+	// Both windows out of the sliding window
+	clock.Advance(20 * time.Second)
+
+	c, err = request()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expect(c, "3", "1050")
+}
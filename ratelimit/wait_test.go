@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReserve(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	rl := New(2, 10)
+	rl.Clock = clock
+	defer rl.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, ok, _ := rl.Reserve("key"); !ok {
+			t.Fatalf("Event %d not reserved", i)
+		}
+	}
+
+	clock.Advance(4 * time.Second)
+	delay, ok, _ := rl.Reserve("key")
+	if ok {
+		t.Error("Limit exceeded")
+	}
+	if delay != 6*time.Second {
+		t.Errorf("Expected 6s delay, got %v", delay)
+	}
+}
+
+func TestReserveAlgorithms(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	tests := map[string]Algorithm{
+		"bucket":  TokenBucket(2, 1),
+		"sliding": SlidingWindow(1, time.Second),
+	}
+
+	for name, a := range tests {
+		rl := NewWithAlgorithm(context.Background(), a)
+		rl.Clock = clock
+
+		rl.Reserve("key")
+		delay, ok, _ := rl.Reserve("key")
+		if ok || delay <= 0 {
+			t.Errorf("%s: expected a delay, got %v", name, delay)
+		}
+
+		clock.Advance(delay)
+		if _, ok, _ := rl.Reserve("key"); !ok {
+			t.Errorf("%s: event not allowed after the delay", name)
+		}
+
+		rl.Close()
+	}
+}
+
+func TestReserveNever(t *testing.T) {
+	rl := New(0, 1)
+	defer rl.Close()
+
+	if _, ok, err := rl.Reserve("key"); ok || err == nil {
+		t.Error("Event over the limit reserved")
+	} else if _, isCost := err.(CostError); !isCost {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, ok := rl.Wait(ctx, "key").(CostError); !ok {
+		t.Error("Wait didn't return a CostError")
+	}
+}
+
+func TestWait(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+
+	rl := New(1, 10)
+	rl.Clock = clock
+	defer rl.Close()
+
+	if err := rl.Wait(context.Background(), "key"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- rl.Wait(context.Background(), "key")
+	}()
+
+	// Wait for the goroutine to block on the clock
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(11 * time.Second)
+	if err := <-done; err != nil {
+		t.Error(err.Error())
+	}
+
+	// Cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		done <- rl.Wait(ctx, "key")
+	}()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// Deadline before the next event
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := rl.Wait(ctx, "key"); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -1,17 +1,20 @@
 package ratelimit
 
 import (
+	"github.com/yarf-framework/extras/realip"
 	"github.com/yarf-framework/yarf"
-	"strconv"
 	"time"
 )
 
 // YarfError is the custom error type compatible with Yarf's YError
-type YarfError struct{}
+type YarfError struct {
+	// Rendered body. Defaults to a plain text message.
+	body string
+}
 
 // Implements the error interface returning the ErrorMsg value of each error.
 func (e *YarfError) Error() string {
-	return "Unauthorized"
+	return "Too Many Requests"
 }
 
 // Code returns the error's HTTP code to be used in the response.
@@ -31,15 +34,129 @@ func (e *YarfError) Msg() string {
 
 // Body returns the error's content body, if needed, to be returned in the HTTP response.
 func (e *YarfError) Body() string {
+	if e.body != "" {
+		return e.body
+	}
+
 	return "Too Many Requests: Try again later."
 }
 
+// DeniedError is returned by the RateLimiter for requests from a denied IP range.
+type DeniedError struct{}
+
+// Implements the error interface returning the ErrorMsg value of each error.
+func (e *DeniedError) Error() string {
+	return "Forbidden"
+}
+
+// Code returns the error's HTTP code to be used in the response.
+func (e *DeniedError) Code() int {
+	return 403
+}
+
+// ID returns the error's ID for further reference.
+func (e *DeniedError) ID() int {
+	return 403
+}
+
+// Msg returns the error's message, used to implement the Error interface.
+func (e *DeniedError) Msg() string {
+	return "Forbidden"
+}
+
+// Body returns the error's content body, if needed, to be returned in the HTTP response.
+func (e *DeniedError) Body() string {
+	return "Forbidden"
+}
+
 // RateLimiter middleware provides request rate limits per IP
 type RateLimiter struct {
 	yarf.Middleware
 
 	// rate limiter
 	rl *RateLimit
+
+	// Extracts the key to count for each request. Defaults to ClientNet64.
+	// Requests with an empty key aren't limited.
+	Key KeyFunc
+
+	// Rules with their own limits. The most specific matching rule is applied to each request,
+	// and requests not matching any rule use the default rate limiter, if any.
+	Rules []*Rule
+
+	// Classifies the clients to match the Rules' Class.
+	Classifier Classifier
+
+	// Rate limit headers to set. Defaults to LegacyHeaders | DraftHeaders.
+	Headers Headers
+
+	// Renders the blocked responses. Defaults to PlainText.
+	Renderer Renderer
+
+	// Client IP ranges never limited, like health checkers and internal networks.
+	// The lists are matched against the IP resolved by the realip middleware, or the connection remote address.
+	Allow *CIDRList
+
+	// Client IP ranges always blocked with a DeniedError. Deny takes precedence over Allow.
+	Deny *CIDRList
+
+	// Additional limits counted on the requests not matching any rule, like a per /48 limit over a per /64 Key.
+	Layers []*Layer
+
+	// Computes how many events each request counts as. Defaults to 1 for every request.
+	// The rate limit headers show the remaining events after the cost of the request.
+	Cost CostFunc
+
+	// Optional escalating bans for the keys exceeding their limit.
+	// Banned keys are blocked without counting until the ban ends.
+	Penalty *Penalty
+
+	// Shadow mode counts the requests normally but never blocks them, to try new limits in production.
+	// The requests that would have been blocked are reported to OnShadow, and no rate limit header is set.
+	Shadow bool
+
+	// Receives the requests that would have been blocked in Shadow mode. Defaults to LogShadow.
+	OnShadow ShadowFunc
+
+	// Optional candidate limits, usually stricter, that always run in shadow mode next to the enforced ones.
+	// Its OnShadow field sets where its reports go.
+	Candidate *RateLimiter
+
+	// Requests allowed and blocked by the default limit
+	tally tally
+}
+
+// verdict is the outcome of the RateLimiter checks for a request, before writing the response.
+type verdict struct {
+	// Matching rule, nil for the default limit
+	rule *Rule
+
+	// Rate limit state, nil when the request wasn't counted
+	status *Status
+
+	// Time of the RateLimit clock after counting
+	now time.Time
+
+	// Why the request is blocked, empty if it's allowed
+	reason string
+
+	// Unexpected counting error
+	err error
+}
+
+// NewRateLimiter creates the middleware using a custom RateLimit, like one created by NewWithAlgorithm.
+func NewRateLimiter(rl *RateLimit) *RateLimiter {
+	return &RateLimiter{
+		rl: rl,
+	}
+}
+
+// NewRuleLimiter creates the middleware that applies the most specific matching rule to each request.
+// Requests not matching any rule aren't limited.
+func NewRuleLimiter(rules ...*Rule) *RateLimiter {
+	return &RateLimiter{
+		Rules: rules,
+	}
 }
 
 // YarfMiddleware constructor receives the requests limit and a time window (in seconds) to allow.
@@ -52,24 +169,263 @@ func YarfMiddleware(limit, window int) *RateLimiter {
 
 // PreDispatch performs the requests counting and handle blocks/
 func (m *RateLimiter) PreDispatch(c *yarf.Context) error {
-	// IP as key
-	key := c.GetClientIP()
+	if m.Candidate != nil {
+		m.Candidate.shadow(c)
+	}
+
+	if m.Shadow {
+		m.shadow(c)
+		return nil
+	}
+
+	v := m.check(c)
+	switch {
+	case v.err != nil:
+		return v.err
+
+	case v.reason == ReasonDeny:
+		return new(DeniedError)
+	}
+
+	// Set rate limit info on headers
+	if v.status != nil {
+		setHeaders(c, m.Headers, *v.status, v.now, v.reason != "")
+	}
+
+	if v.reason != "" {
+		return m.blocked(c, *v.status)
+	}
+
+	return nil
+}
+
+// shadow runs the checks for a request and reports it if it would have been blocked.
+func (m *RateLimiter) shadow(c *yarf.Context) {
+	v := m.check(c)
+	if v.reason == "" {
+		return
+	}
+
+	e := ShadowEvent{
+		Reason: v.reason,
+		Method: c.Request.Method,
+		Path:   c.Request.URL.Path,
+	}
+	if v.status != nil {
+		e.Status = *v.status
+	} else {
+		e.Key = realip.Verified(c)
+	}
+	if v.rule != nil {
+		e.Rule = v.rule.Name
+	}
+
+	report := m.OnShadow
+	if report == nil {
+		report = LogShadow
+	}
+	report(c, e)
+}
+
+// check applies the IP lists, rules and limits to a request, without writing the response.
+func (m *RateLimiter) check(c *yarf.Context) verdict {
+	// IP lists, never matched against the client headers
+	if m.Allow != nil || m.Deny != nil {
+		ip := realip.Verified(c)
+
+		if m.Deny != nil && m.Deny.ContainsString(ip) {
+			return verdict{reason: ReasonDeny}
+		}
+		if m.Allow != nil && m.Allow.ContainsString(ip) {
+			return verdict{}
+		}
+	}
+
+	rl, keyFunc, costFunc, layers := m.rl, m.Key, m.Cost, m.Layers
+
+	// Rule limits
+	rule := m.Match(c)
+	if rule != nil {
+		rl, keyFunc, layers = rule.Limit, rule.Key, rule.Layers
+		if rule.Cost != nil {
+			costFunc = rule.Cost
+		}
+	}
+
+	// Request cost
+	cost := 1
+	if costFunc != nil {
+		cost = costFunc(c)
+	}
+
+	v := verdict{rule: rule}
+	if rl != nil {
+		v = m.count(rl, keyOrDefault(keyFunc)(c), cost)
+		v.rule = rule
+	}
+
+	// Layers are counted in order until one blocks, and the most restrictive one sets the headers.
+	for _, l := range layers {
+		if v.reason != "" || v.err != nil {
+			break
+		}
+		if l.Limit == nil {
+			continue
+		}
+
+		lv := m.count(l.Limit, keyOrDefault(l.Key)(c), cost)
+		if lv.status == nil && lv.err == nil {
+			continue
+		}
+		if v.status == nil || lv.reason != "" || lv.err != nil || lv.status.Remaining < v.status.Remaining {
+			lv.rule = rule
+			v = lv
+		}
+	}
+
+	// Totals
+	if rule != nil {
+		rule.tally.record(v)
+	} else {
+		m.tally.record(v)
+	}
+
+	return v
+}
+
+// keyOrDefault returns the KeyFunc, or ClientNet64 if it's nil, as the default key.
+func keyOrDefault(key KeyFunc) KeyFunc {
+	if key == nil {
+		return ClientNet64
+	}
+
+	return key
+}
+
+// count performs the counting of a key with the cost of the request and gets its rate limit state.
+// Blocked requests also get the time to wait before retrying.
+func (m *RateLimiter) count(rl *RateLimit, key string, cost int) verdict {
+	if key == "" || cost <= 0 {
+		return verdict{}
+	}
+
+	// Banned keys
+	if m.Penalty != nil {
+		if ban, ok := m.Penalty.Banned(key); ok {
+			rl.ban(key, ban.Until, false)
+
+			return m.banned(rl, key, ban)
+		}
+	}
 
 	// Count
-	err := m.rl.Count(key)
+	err := rl.CountN(key, cost)
 	if err != nil {
-		if _, ok := err.(RateLimitError); ok {
-			return &YarfError{}
+		if _, ok := err.(RateLimitError); !ok {
+			return verdict{err: err}
 		}
 
-		return err
+		if m.Penalty != nil {
+			ban := m.Penalty.Offend(key)
+			rl.ban(key, ban.Until, true)
+
+			return m.banned(rl, key, ban)
+		}
 	}
 
-	// Set rate limit info on headers
-	rate := m.rl.Get(key)
-	c.Response.Header().Set("X-RateLimit-Limit", strconv.Itoa(rate.Limit))
-	c.Response.Header().Set("X-RateLimit-Remaining", strconv.Itoa(rate.Limit-rate.EventCount))
-	c.Response.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(rate.Start.Add(time.Second*time.Duration(rate.Window)).Unix())))
+	l := rl.Get(key)
+	s := &Status{Key: key}
+	s.Limit, s.Remaining, s.Reset = l.Status()
+
+	v := verdict{status: s, now: rl.now()}
+	if err != nil {
+		s.RetryAfter = l.DelayN(cost)
+		v.reason = ReasonLimit
+	}
+
+	return v
+}
+
+// banned blocks a request from a banned key until the ban ends.
+func (m *RateLimiter) banned(rl *RateLimit, key string, ban Ban) verdict {
+	s := &Status{Key: key}
+	s.Limit, _, _ = rl.Get(key).Status()
+	s.Reset = ban.Until
+	s.RetryAfter = ban.Until.Sub(now(m.Penalty.Clock))
+
+	return verdict{status: s, now: rl.now(), reason: ReasonBan}
+}
+
+// blocked renders the error returned for a blocked request.
+func (m *RateLimiter) blocked(c *yarf.Context, s Status) error {
+	render := m.Renderer
+	if render == nil {
+		render = PlainText
+	}
+
+	contentType, body := render(c, s)
+	c.Response.Header().Set("Content-Type", contentType)
+
+	return &YarfError{body: body}
+}
+
+// Match returns the most specific rule matching the request, or nil if none matches.
+// On a tie, the first rule wins.
+func (m *RateLimiter) Match(c *yarf.Context) *Rule {
+	if len(m.Rules) == 0 {
+		return nil
+	}
+
+	var class string
+	if m.Classifier != nil {
+		class = m.Classifier(c)
+	}
+
+	var match *Rule
+	for _, r := range m.Rules {
+		if r.Limit == nil || !r.Match(c, class) {
+			continue
+		}
+		if match == nil || r.Specificity() > match.Specificity() {
+			match = r
+		}
+	}
+
+	return match
+}
+
+// Close stops the background work of the rate limiter, its rules and its Candidate.
+// Should be called when the middleware isn't used anymore, like on short-lived sub-apps.
+func (m *RateLimiter) Close() error {
+	if m.rl != nil {
+		m.rl.Close()
+	}
+
+	for _, l := range m.Layers {
+		if l.Limit != nil {
+			l.Limit.Close()
+		}
+	}
+
+	for _, r := range m.Rules {
+		if r.Limit != nil {
+			r.Limit.Close()
+		}
+
+		for _, l := range r.Layers {
+			if l.Limit != nil {
+				l.Limit.Close()
+			}
+		}
+	}
+
+	if m.Penalty != nil {
+		m.Penalty.Close()
+	}
+
+	if m.Candidate != nil {
+		m.Candidate.Close()
+	}
 
 	return nil
 }
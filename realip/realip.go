@@ -0,0 +1,189 @@
+package realip
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolver finds the client IP address of a request sent through trusted proxies.
+// The proxy headers are read from the right, skipping the trusted hops,
+// so addresses added by the client itself can't be used to spoof its IP.
+type Resolver struct {
+	// Proxies allowed to set the client address, like the load balancers.
+	Trusted []*net.IPNet
+
+	// Header set by the trusted proxies: "X-Forwarded-For", "Forwarded" (RFC 7239) or "X-Real-IP".
+	// Only this header is read, as the proxies pass the others through from the client. Defaults to "X-Forwarded-For".
+	Header string
+}
+
+// DefaultHeader is the header read by a Resolver without Header.
+const DefaultHeader = "X-Forwarded-For"
+
+// NewResolver creates a Resolver trusting the given proxy addresses or CIDR ranges, like "10.0.0.0/8".
+func NewResolver(trusted ...string) (*Resolver, error) {
+	r := new(Resolver)
+
+	for _, t := range trusted {
+		n, err := parseCIDR(t)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Trusted = append(r.Trusted, n)
+	}
+
+	return r, nil
+}
+
+// parseCIDR parses a CIDR range or a single IP address.
+func parseCIDR(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: s}
+		}
+
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+
+	_, n, err := net.ParseCIDR(s)
+
+	return n, err
+}
+
+// IsTrusted reports if an IP address belongs to a trusted proxy.
+func (r *Resolver) IsTrusted(ip net.IP) bool {
+	for _, n := range r.Trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolve returns the client IP address of a request.
+// Requests not coming from a trusted proxy get their remote address.
+// Otherwise, it's the rightmost untrusted address on the proxy header,
+// or the leftmost one if all of them are trusted.
+// If a hop isn't a valid IP address, like "unknown", the last trusted address is returned.
+func (r *Resolver) Resolve(req *http.Request) string {
+	remote := parseIP(req.RemoteAddr)
+	if remote == nil {
+		return ""
+	}
+	if !r.IsTrusted(remote) {
+		return remote.String()
+	}
+
+	h := r.Header
+	if h == "" {
+		h = DefaultHeader
+	}
+
+	values := req.Header.Values(h)
+	if len(values) == 0 {
+		return remote.String()
+	}
+
+	var hops []string
+	if http.CanonicalHeaderKey(h) == "Forwarded" {
+		hops = forwarded(values)
+	} else {
+		hops = split(strings.Join(values, ","), ',')
+	}
+
+	return r.walk(remote, hops).String()
+}
+
+// walk goes through the hops from the right, starting at the trusted remote address.
+func (r *Resolver) walk(ip net.IP, hops []string) net.IP {
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseIP(hops[i])
+		if hop == nil {
+			return ip
+		}
+
+		ip = hop
+		if !r.IsTrusted(ip) {
+			return ip
+		}
+	}
+
+	return ip
+}
+
+// forwarded returns the "for" parameters of the RFC 7239 Forwarded header values, from left to right.
+// Elements without "for" are returned empty, so they stop the walk.
+func forwarded(values []string) []string {
+	var hops []string
+
+	for _, v := range values {
+		for _, element := range split(v, ',') {
+			var hop string
+
+			for _, pair := range split(element, ';') {
+				if i := strings.IndexByte(pair, '='); i > 0 && strings.EqualFold(strings.TrimSpace(pair[:i]), "for") {
+					hop = strings.Trim(strings.TrimSpace(pair[i+1:]), `"`)
+				}
+			}
+
+			hops = append(hops, hop)
+		}
+	}
+
+	return hops
+}
+
+// split splits a header value by sep, ignoring the separators within quoted strings.
+func split(s string, sep byte) []string {
+	var parts []string
+
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+
+		case s[i] == '"':
+			quoted = !quoted
+
+		case s[i] == sep && !quoted:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// remoteIP returns the IP address of the connection, or "" if it can't be parsed.
+func remoteIP(req *http.Request) string {
+	if ip := parseIP(req.RemoteAddr); ip != nil {
+		return ip.String()
+	}
+
+	return ""
+}
+
+// parseIP parses an address with an optional port, like "192.0.2.1:1234" or "[2001:db8::1]:1234".
+func parseIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+
+	ip := net.ParseIP(strings.Trim(s, "[]"))
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+
+	return ip
+}
@@ -0,0 +1,112 @@
+package realip
+
+import (
+	"github.com/yarf-framework/extras/context/data"
+	"github.com/yarf-framework/yarf"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newRequest(remote string, headers map[string]string) *http.Request {
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.RemoteAddr = remote
+	for k, v := range headers {
+		r.Header.Add(k, v)
+	}
+
+	return r
+}
+
+func TestResolve(t *testing.T) {
+	r, err := NewResolver("10.0.0.0/8", "2001:db8::1")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, tc := range []struct {
+		name    string
+		header  string
+		remote  string
+		headers map[string]string
+		ip      string
+	}{
+		{"untrusted remote", "", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7"},
+		{"no header", "", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"x-forwarded-for", "", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		{"spoofed x-forwarded-for", "", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"all trusted", "", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"invalid hop", "", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, garbage, 10.0.0.2"}, "10.0.0.2"},
+		{"x-real-ip", "X-Real-IP", "10.0.0.1:1234", map[string]string{"X-Real-IP": "198.51.100.1"}, "198.51.100.1"},
+		{"forwarded", "Forwarded", "10.0.0.1:1234", map[string]string{"Forwarded": `for=1.2.3.4, for="198.51.100.1:4711";proto=https, for=10.0.0.2`}, "198.51.100.1"},
+		{"forwarded ipv6", "Forwarded", "[2001:db8::1]:1234", map[string]string{"Forwarded": `For="[2001:db8:cafe::17]:4711"`}, "2001:db8:cafe::17"},
+		{"forwarded obfuscated", "Forwarded", "10.0.0.1:1234", map[string]string{"Forwarded": `for=198.51.100.1, for=_hidden`}, "10.0.0.1"},
+	} {
+		r.Header = tc.header
+		if ip := r.Resolve(newRequest(tc.remote, tc.headers)); ip != tc.ip {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.ip, ip)
+		}
+	}
+
+	if _, err := NewResolver("not an ip"); err == nil {
+		t.Error("Invalid trusted proxy accepted")
+	}
+}
+
+func TestClientHeadersIgnored(t *testing.T) {
+	r, _ := NewResolver("10.0.0.0/8")
+
+	// The proxy appends to X-Forwarded-For and passes through the Forwarded header sent by the client.
+	for _, spoofed := range []string{"for=198.51.100.66", "for=10.9.9.9"} {
+		req := newRequest("10.0.0.5:1234", map[string]string{
+			"X-Forwarded-For": "203.0.113.7",
+			"Forwarded":       spoofed,
+			"X-Real-IP":       "10.9.9.9",
+		})
+
+		if ip := r.Resolve(req); ip != "203.0.113.7" {
+			t.Errorf("Forwarded %s: expected 203.0.113.7, got %s", spoofed, ip)
+		}
+	}
+
+	// Only the configured header is read
+	r.Header = "Forwarded"
+	if ip := r.Resolve(newRequest("10.0.0.5:1234", map[string]string{"X-Forwarded-For": "198.51.100.66"})); ip != "10.0.0.5" {
+		t.Errorf("Expected 10.0.0.5, got %s", ip)
+	}
+}
+
+func TestMultipleHeaderLines(t *testing.T) {
+	r, _ := NewResolver("10.0.0.0/8")
+
+	req := newRequest("10.0.0.1:1234", nil)
+	req.Header.Add("X-Forwarded-For", "1.2.3.4")
+	req.Header.Add("X-Forwarded-For", "198.51.100.1, 10.0.0.2")
+
+	if ip := r.Resolve(req); ip != "198.51.100.1" {
+		t.Errorf("Expected 198.51.100.1, got %s", ip)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	m, err := YarfMiddleware("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	c := yarf.NewContext(newRequest("10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}), httptest.NewRecorder())
+	c.Data = new(data.StrData)
+
+	if err := m.PreDispatch(c); err != nil {
+		t.Fatal(err.Error())
+	}
+	if ip := Get(c); ip != "198.51.100.1" {
+		t.Errorf("Expected 198.51.100.1, got %s", ip)
+	}
+
+	// Not resolved
+	c = yarf.NewContext(newRequest("10.0.0.1:1234", nil), httptest.NewRecorder())
+	if ip := Get(c); ip != c.GetClientIP() {
+		t.Errorf("Expected %s, got %s", c.GetClientIP(), ip)
+	}
+}
@@ -0,0 +1,83 @@
+package realip
+
+import (
+	"github.com/yarf-framework/yarf"
+)
+
+// RealIP middleware resolves the client IP address of each request using a Resolver,
+// and sets it into the "_realIP" index on Context Data.
+// The ratelimit, logger and auth packages use it when it's inserted before them.
+type RealIP struct {
+	yarf.Middleware
+
+	// Resolver of the client IP address
+	Resolver *Resolver
+}
+
+// YarfMiddleware creates the middleware trusting the given proxy addresses or CIDR ranges, like "10.0.0.0/8".
+func YarfMiddleware(trusted ...string) (*RealIP, error) {
+	r, err := NewResolver(trusted...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RealIP{
+		Resolver: r,
+	}, nil
+}
+
+// PreDispatch resolves the client IP and sets the value.
+// It needs a Context Data object, like the one set by the context/data middleware.
+func (m *RealIP) PreDispatch(c *yarf.Context) error {
+	if c.Data == nil {
+		return nil
+	}
+
+	r := m.Resolver
+	if r == nil {
+		r = new(Resolver)
+	}
+
+	if ip := r.Resolve(c.Request); ip != "" {
+		c.Data.Set("_realIP", ip)
+	}
+
+	return nil
+}
+
+// Get returns the client IP address resolved by the RealIP middleware.
+// If the middleware didn't run on the request, it returns c.GetClientIP().
+func Get(c *yarf.Context) string {
+	if ip, ok := resolved(c); ok {
+		return ip
+	}
+
+	return c.GetClientIP()
+}
+
+// Verified returns the client IP address resolved by the RealIP middleware.
+// If the middleware didn't run on the request, it returns the remote address of the connection,
+// so it never trusts the proxy headers sent by the client. Use it for access control, like IP allow lists.
+func Verified(c *yarf.Context) string {
+	if ip, ok := resolved(c); ok {
+		return ip
+	}
+
+	return remoteIP(c.Request)
+}
+
+// resolved returns the client IP address set by the RealIP middleware, if any.
+func resolved(c *yarf.Context) (string, bool) {
+	if c.Data == nil {
+		return "", false
+	}
+
+	ip, err := c.Data.Get("_realIP")
+	if err != nil {
+		return "", false
+	}
+
+	s, ok := ip.(string)
+
+	return s, ok && s != ""
+}